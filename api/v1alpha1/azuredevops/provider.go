@@ -0,0 +1,65 @@
+package azuredevops
+
+import (
+	"errors"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1"
+)
+
+func init() {
+	redhatcopv1alpha1.RegisterProvider(&provider{})
+}
+
+// provider registers azure devops with the GitWebhook controller
+type provider struct{}
+
+func (p *provider) Name() string {
+	return "azureDevOps"
+}
+
+func (p *provider) ServerConfig(spec *redhatcopv1alpha1.GitWebhookSpec) redhatcopv1alpha1.ServerConfig {
+	if spec.AzureDevOps == nil {
+		return nil
+	}
+	return spec.AzureDevOps
+}
+
+func (p *provider) NewWebHook(gitWebhook *redhatcopv1alpha1.GitWebhook) redhatcopv1alpha1.WebHook {
+	return FromGitWebhook(gitWebhook)
+}
+
+func (p *provider) CredentialKeys() [][]string {
+	return [][]string{{"token"}}
+}
+
+// Events only the git events of the tfs publisher are scoped to a repository, the other events have other publishers and publisher inputs
+func (p *provider) Events() []string {
+	return []string{
+		"git.push",
+		"git.pullrequest.created",
+		"git.pullrequest.updated",
+		"git.pullrequest.merged",
+		"ms.vss-code.git-pullrequest-comment-event",
+	}
+}
+
+func (p *provider) CanonicalEvents() map[string][]string {
+	return map[string][]string{
+		"push":         {"git.push"},
+		"tag":          {"git.push"},
+		"pull_request": {"git.pullrequest.created", "git.pullrequest.updated", "git.pullrequest.merged"},
+		"comment":      {"ms.vss-code.git-pullrequest-comment-event"},
+	}
+}
+
+func (p *provider) SupportsOwnerWebHooks() bool {
+	return false
+}
+
+// ValidateSpec rejects the adoption policies other than Adopt, the subscriptions of the repository that call the webhook url are always managed
+func (p *provider) ValidateSpec(spec *redhatcopv1alpha1.GitWebhookSpec) error {
+	if spec.AdoptionPolicy != "" && spec.AdoptionPolicy != "Adopt" {
+		return errors.New("adoptionPolicy " + spec.AdoptionPolicy + " is not supported by azure devops, the subscriptions of the repository that call the webhook url are always managed")
+	}
+	return nil
+}
@@ -0,0 +1,378 @@
+package azuredevops
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1"
+	"github.com/redhat-cop/gitwebhook-operator/api/v1alpha1/rest"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// AzureDevOpsWebHook manages the service hook subscriptions of a repository.
+// Azure DevOps needs a subscription per event type, so one GitWebhook maps to a subscription per event.
+type AzureDevOpsWebHook struct {
+	gitWebhook *redhatcopv1alpha1.GitWebhook
+	repository *repository
+	client     *rest.Client
+	applied    *redhatcopv1alpha1.AppliedWebHook
+}
+
+// subscription is a service hook subscription, see https://learn.microsoft.com/en-us/rest/api/azure/devops/hooks/subscriptions/create
+type subscription struct {
+	ID               string            `json:"id,omitempty"`
+	PublisherID      string            `json:"publisherId"`
+	EventType        string            `json:"eventType"`
+	ResourceVersion  string            `json:"resourceVersion"`
+	ConsumerID       string            `json:"consumerId"`
+	ConsumerActionID string            `json:"consumerActionId"`
+	PublisherInputs  map[string]string `json:"publisherInputs"`
+	ConsumerInputs   map[string]string `json:"consumerInputs"`
+	Status           string            `json:"status,omitempty"`
+}
+
+type subscriptionList struct {
+	Value []*subscription `json:"value"`
+}
+
+type repository struct {
+	ID      string `json:"id"`
+	Project struct {
+		ID string `json:"id"`
+	} `json:"project"`
+}
+
+const (
+	apiVersion        = "api-version=7.0"
+	publisherID       = "tfs"
+	consumerID        = "webHooks"
+	consumerActionID  = "httpRequest"
+	basicAuthUsername = "gitwebhook"
+	enabled           = "enabled"
+	disabledByUser    = "disabledByUser"
+)
+
+// resourceVersions contains the event types whose payload version is not 1.0
+var resourceVersions = map[string]string{
+	"ms.vss-code.git-pullrequest-comment-event": "2.0",
+}
+
+var _ redhatcopv1alpha1.WebHook = &AzureDevOpsWebHook{}
+var _ redhatcopv1alpha1.DriftDetector = &AzureDevOpsWebHook{}
+var _ redhatcopv1alpha1.AppliedWebHookReporter = &AzureDevOpsWebHook{}
+
+func FromGitWebhook(gitwebhook *redhatcopv1alpha1.GitWebhook) *AzureDevOpsWebHook {
+	return &AzureDevOpsWebHook{
+		gitWebhook: gitwebhook,
+	}
+}
+
+func (m *AzureDevOpsWebHook) Reconcile(ctx context.Context) error {
+	return m.reconcile(ctx)
+}
+
+func (m *AzureDevOpsWebHook) Delete(ctx context.Context) error {
+	return m.deleteIfExists(ctx)
+}
+
+func (m *AzureDevOpsWebHook) AppliedWebHook() *redhatcopv1alpha1.AppliedWebHook {
+	return m.applied
+}
+
+// IsEquivalent returns whether the subscriptions on azure devops match the GitWebhook, one subscription per event
+func (m *AzureDevOpsWebHook) IsEquivalent(ctx context.Context) (bool, error) {
+	log := log.FromContext(ctx)
+	desiredSubscriptions, err := m.toSubscriptions(ctx)
+	if err != nil {
+		log.Error(err, "unable to convert to azure devops subscriptions")
+		return false, err
+	}
+	actualSubscriptions, err := m.getSubscriptions(ctx)
+	if err != nil {
+		log.Error(err, "error while retrieving current subscriptions")
+		return false, err
+	}
+	secret, err := m.gitWebhook.GetWebhookSecret(ctx)
+	if err != nil {
+		log.Error(err, "unable to retrieve webhook secret")
+		return false, err
+	}
+	if !m.gitWebhook.IsWebhookSecretApplied(secret) || len(desiredSubscriptions) != len(actualSubscriptions) {
+		return false, nil
+	}
+	for _, desired := range desiredSubscriptions {
+		actual, found := actualSubscriptions[desired.EventType]
+		if !found || !m.isEquivalent(desired, actual) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (m *AzureDevOpsWebHook) subscriptionsPath() string {
+	return url.PathEscape(m.gitWebhook.Spec.AzureDevOps.Organization) + "/_apis/hooks/subscriptions"
+}
+
+func (m *AzureDevOpsWebHook) getClient(ctx context.Context) (*rest.Client, error) {
+	if m.client != nil {
+		return m.client, nil
+	}
+	log := log.FromContext(ctx)
+	token, err := m.gitWebhook.GetGitCredential(ctx, m.gitWebhook.Spec.AzureDevOps)
+	if err != nil {
+		log.Error(err, "Unable to retrieve azure devops credential", "secret", m.gitWebhook.Spec.AzureDevOps.GitServerCredentials.Name)
+		return nil, err
+	}
+	// personal access tokens are sent as basic auth password with an empty username
+	m.client, err = rest.NewClient(m.gitWebhook.Spec.AzureDevOps.AzureDevOpsAPIServerURL, rest.BasicAuth("", token))
+	if err != nil {
+		log.Error(err, "Unable to parse azure devops url", "url", m.gitWebhook.Spec.AzureDevOps.AzureDevOpsAPIServerURL)
+		return nil, err
+	}
+	return m.client, nil
+}
+
+func (m *AzureDevOpsWebHook) getRepository(ctx context.Context) (*repository, error) {
+	if m.repository != nil {
+		return m.repository, nil
+	}
+	log := log.FromContext(ctx)
+	client, err := m.getClient(ctx)
+	if err != nil {
+		log.Error(err, "unable to create azure devops client")
+		return nil, err
+	}
+	repository := &repository{}
+	_, err = client.Do(ctx, http.MethodGet, url.PathEscape(m.gitWebhook.Spec.AzureDevOps.Organization)+"/"+url.PathEscape(m.gitWebhook.Spec.RepositoryOwner)+"/_apis/git/repositories/"+url.PathEscape(m.gitWebhook.Spec.RepositoryName)+"?"+apiVersion, nil, repository)
+	if err != nil {
+		log.Error(err, "unable to retrieve repository", "repository", m.gitWebhook.Spec.RepositoryOwner+"/"+m.gitWebhook.Spec.RepositoryName)
+		return nil, err
+	}
+	m.repository = repository
+	return repository, nil
+}
+
+func (m *AzureDevOpsWebHook) toSubscriptions(ctx context.Context) ([]*subscription, error) {
+	log := log.FromContext(ctx)
+	secret, err := m.gitWebhook.GetWebhookSecret(ctx)
+	if err != nil {
+		log.Error(err, "unable to retrieve webhook secret")
+		return nil, err
+	}
+	repository, err := m.getRepository(ctx)
+	if err != nil {
+		log.Error(err, "unable to retrieve repository")
+		return nil, err
+	}
+	status := enabled
+	if !m.gitWebhook.Spec.Active {
+		status = disabledByUser
+	}
+	webhookURL, err := m.gitWebhook.GetWebhookURL(ctx)
+	if err != nil {
+		log.Error(err, "unable to retrieve webhook url")
+		return nil, err
+	}
+	events, err := m.gitWebhook.GetNativeEvents()
+	if err != nil {
+		log.Error(err, "unable to translate events")
+		return nil, err
+	}
+	subscriptions := []*subscription{}
+	for _, event := range events {
+		resourceVersion, found := resourceVersions[event]
+		if !found {
+			resourceVersion = "1.0"
+		}
+		subscription := subscription{
+			PublisherID:      publisherID,
+			EventType:        event,
+			ResourceVersion:  resourceVersion,
+			ConsumerID:       consumerID,
+			ConsumerActionID: consumerActionID,
+			PublisherInputs: map[string]string{
+				"projectId":  repository.Project.ID,
+				"repository": repository.ID,
+			},
+			ConsumerInputs: map[string]string{
+				"url":                  webhookURL,
+				"acceptUntrustedCerts": strconv.FormatBool(m.gitWebhook.Spec.InsecureSSL),
+			},
+			Status: status,
+		}
+		if secret != "" {
+			subscription.ConsumerInputs["basicAuthUsername"] = basicAuthUsername
+			subscription.ConsumerInputs["basicAuthPassword"] = secret
+		}
+		subscriptions = append(subscriptions, &subscription)
+	}
+	return subscriptions, nil
+}
+
+// getSubscriptions returns the subscriptions of this repository that call the webhook url, by event type
+func (m *AzureDevOpsWebHook) getSubscriptions(ctx context.Context) (map[string]*subscription, error) {
+	log := log.FromContext(ctx)
+	client, err := m.getClient(ctx)
+	if err != nil {
+		log.Error(err, "unable to create azure devops client")
+		return nil, err
+	}
+	repository, err := m.getRepository(ctx)
+	if err != nil {
+		log.Error(err, "unable to retrieve repository")
+		return nil, err
+	}
+	webhookURL, err := m.gitWebhook.GetWebhookURL(ctx)
+	if err != nil {
+		log.Error(err, "unable to retrieve webhook url")
+		return nil, err
+	}
+	list := subscriptionList{}
+	_, err = client.Do(ctx, http.MethodGet, m.subscriptionsPath()+"?"+apiVersion+"&publisherId="+publisherID+"&consumerId="+consumerID+"&consumerActionId="+consumerActionID, nil, &list)
+	if err != nil {
+		log.Error(err, "unable to list service hook subscriptions", "for repo", m.gitWebhook.Spec.RepositoryOwner+"/"+m.gitWebhook.Spec.RepositoryName)
+		return nil, err
+	}
+	subscriptions := map[string]*subscription{}
+	for _, subscription := range list.Value {
+		if subscription.PublisherInputs["repository"] == repository.ID && m.gitWebhook.MatchesWebhookURL(subscription.ConsumerInputs["url"], webhookURL) {
+			subscriptions[subscription.EventType] = subscription
+		}
+	}
+	return subscriptions, nil
+}
+
+func (m *AzureDevOpsWebHook) isEquivalent(desired *subscription, actual *subscription) bool {
+	// azure devops adds its own inputs and masks the secrets, so only the inputs we set are compared
+	publisherInputs := map[string]string{}
+	for key := range desired.PublisherInputs {
+		publisherInputs[key] = actual.PublisherInputs[key]
+	}
+	consumerInputs := map[string]string{}
+	for key := range desired.ConsumerInputs {
+		if key == "basicAuthPassword" {
+			consumerInputs[key] = desired.ConsumerInputs[key]
+			continue
+		}
+		consumerInputs[key] = actual.ConsumerInputs[key]
+	}
+	return desired.PublisherID == actual.PublisherID &&
+		desired.ResourceVersion == actual.ResourceVersion &&
+		desired.Status == actual.Status &&
+		reflect.DeepEqual(desired.PublisherInputs, publisherInputs) &&
+		reflect.DeepEqual(desired.ConsumerInputs, consumerInputs)
+}
+
+func (m *AzureDevOpsWebHook) reconcile(ctx context.Context) error {
+	log := log.FromContext(ctx)
+	desiredSubscriptions, err := m.toSubscriptions(ctx)
+	if err != nil {
+		log.Error(err, "unable to convert to azure devops subscriptions")
+		return err
+	}
+	actualSubscriptions, err := m.getSubscriptions(ctx)
+	if err != nil {
+		log.Error(err, "error while retrieving current subscriptions")
+		return err
+	}
+	client, err := m.getClient(ctx)
+	if err != nil {
+		log.Error(err, "error get azure devops client")
+		return err
+	}
+	secret, err := m.gitWebhook.GetWebhookSecret(ctx)
+	if err != nil {
+		log.Error(err, "unable to retrieve webhook secret")
+		return err
+	}
+	// the secret is masked by azure devops, a changed secret is detected with the fingerprint of the applied secret
+	secretApplied := m.gitWebhook.IsWebhookSecretApplied(secret)
+	for _, desired := range desiredSubscriptions {
+		actual, found := actualSubscriptions[desired.EventType]
+		delete(actualSubscriptions, desired.EventType)
+		if !found {
+			//we need to create
+			_, err = client.Do(ctx, http.MethodPost, m.subscriptionsPath()+"?"+apiVersion, desired, nil)
+			if err != nil {
+				log.Error(err, "unable to create subscription", "event", desired.EventType)
+				return err
+			}
+			continue
+		}
+		if secretApplied && m.isEquivalent(desired, actual) {
+			continue
+		}
+		//we need to update
+		desired.ID = actual.ID
+		_, err = client.Do(ctx, http.MethodPut, m.subscriptionsPath()+"/"+url.PathEscape(actual.ID)+"?"+apiVersion, desired, nil)
+		if err != nil {
+			log.Error(err, "unable to update subscription", "event", desired.EventType)
+			return err
+		}
+	}
+	// the remaining subscriptions are for events that are no longer requested
+	for _, actual := range actualSubscriptions {
+		err = m.deleteSubscription(ctx, actual)
+		if err != nil {
+			return err
+		}
+	}
+	m.recordAppliedWebHook(desiredSubscriptions)
+	return nil
+}
+
+// recordAppliedWebHook records the subscriptions sent to or found in sync on azure devops, they share their settings
+func (m *AzureDevOpsWebHook) recordAppliedWebHook(subscriptions []*subscription) {
+	applied := &redhatcopv1alpha1.AppliedWebHook{
+		RepositoryID: m.repository.ID,
+		Events:       []string{},
+	}
+	for _, subscription := range subscriptions {
+		applied.Events = append(applied.Events, subscription.EventType)
+	}
+	sort.Strings(applied.Events)
+	if len(subscriptions) > 0 {
+		active := subscriptions[0].Status == enabled
+		insecureSSL := subscriptions[0].ConsumerInputs["acceptUntrustedCerts"] == "true"
+		applied.Settings = redhatcopv1alpha1.AppliedWebhookSettings{
+			Active:      &active,
+			InsecureSSL: &insecureSSL,
+		}
+	}
+	m.applied = applied
+}
+
+func (m *AzureDevOpsWebHook) deleteSubscription(ctx context.Context, subscription *subscription) error {
+	log := log.FromContext(ctx)
+	client, err := m.getClient(ctx)
+	if err != nil {
+		log.Error(err, "error get azure devops client")
+		return err
+	}
+	_, err = client.Do(ctx, http.MethodDelete, m.subscriptionsPath()+"/"+url.PathEscape(subscription.ID)+"?"+apiVersion, nil, nil)
+	if err != nil && !rest.IsNotFound(err) {
+		log.Error(err, "unable to delete subscription", "event", subscription.EventType)
+		return err
+	}
+	return nil
+}
+
+func (m *AzureDevOpsWebHook) deleteIfExists(ctx context.Context) error {
+	log := log.FromContext(ctx)
+	subscriptions, err := m.getSubscriptions(ctx)
+	if err != nil {
+		log.Error(err, "error while retrieving subscriptions")
+		return err
+	}
+	for _, subscription := range subscriptions {
+		err = m.deleteSubscription(ctx, subscription)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
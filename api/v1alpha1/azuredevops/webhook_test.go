@@ -0,0 +1,162 @@
+package azuredevops
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1"
+	"github.com/redhat-cop/gitwebhook-operator/api/v1alpha1/rest"
+	"github.com/redhat-cop/gitwebhook-operator/api/v1alpha1/webhooktest"
+)
+
+const testSubscriptionsPath = "/org/_apis/hooks/subscriptions"
+
+// fakeServer serves the repository and the service hook subscriptions of an organization, the subscriptions are listed at once as azure devops does not paginate them
+type fakeServer struct {
+	*webhooktest.Server
+	subscriptions []*subscription
+	lastID        int
+}
+
+func newFakeServer(t *testing.T, subscriptions ...*subscription) *fakeServer {
+	s := &fakeServer{subscriptions: subscriptions}
+	s.Server = webhooktest.NewServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("api-version") != "7.0" {
+			t.Errorf("missing api version in %s", r.URL)
+		}
+		id := strings.TrimPrefix(r.URL.Path, testSubscriptionsPath+"/")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/org/project/_apis/git/repositories/app":
+			json.NewEncoder(w).Encode(map[string]interface{}{"id": "repo-id", "project": map[string]string{"id": "project-id"}})
+		case r.Method == http.MethodGet && r.URL.Path == testSubscriptionsPath:
+			json.NewEncoder(w).Encode(subscriptionList{Value: s.subscriptions})
+		case r.Method == http.MethodPost && r.URL.Path == testSubscriptionsPath:
+			created := &subscription{}
+			json.NewDecoder(r.Body).Decode(created)
+			s.lastID++
+			created.ID = "sub-" + strconv.Itoa(s.lastID)
+			s.subscriptions = append(s.subscriptions, created)
+			json.NewEncoder(w).Encode(created)
+		case r.Method == http.MethodPut && id != r.URL.Path:
+			for i, existing := range s.subscriptions {
+				if existing.ID == id {
+					updated := &subscription{}
+					json.NewDecoder(r.Body).Decode(updated)
+					s.subscriptions[i] = updated
+					json.NewEncoder(w).Encode(updated)
+					return
+				}
+			}
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodDelete && id != r.URL.Path:
+			for i, existing := range s.subscriptions {
+				if existing.ID == id {
+					s.subscriptions = append(s.subscriptions[:i], s.subscriptions[i+1:]...)
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+			}
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			webhooktest.UnexpectedRequest(t, w, r)
+		}
+	})
+	return s
+}
+
+// eventTypes returns the event types of the subscriptions of the repository, sorted
+func (s *fakeServer) eventTypes() []string {
+	eventTypes := []string{}
+	for _, subscription := range s.subscriptions {
+		if subscription.PublisherInputs["repository"] == "repo-id" && subscription.ConsumerInputs["url"] == "https://hooks.example.com/app" {
+			eventTypes = append(eventTypes, subscription.EventType)
+		}
+	}
+	sort.Strings(eventTypes)
+	return eventTypes
+}
+
+func newTestWebHook(t *testing.T, server *fakeServer, gitWebhook *redhatcopv1alpha1.GitWebhook) *AzureDevOpsWebHook {
+	client, err := rest.NewClient(server.URL, rest.BasicAuth("", "token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &AzureDevOpsWebHook{gitWebhook: gitWebhook, client: client}
+}
+
+func TestAzureDevOpsWebHookLifecycle(t *testing.T) {
+	server := newFakeServer(t,
+		&subscription{ID: "other-repo", EventType: "git.push", PublisherInputs: map[string]string{"repository": "other-repo-id"}, ConsumerInputs: map[string]string{"url": "https://hooks.example.com/app"}},
+		&subscription{ID: "other-url", EventType: "git.push", PublisherInputs: map[string]string{"repository": "repo-id"}, ConsumerInputs: map[string]string{"url": "https://other.example.com/app"}},
+	)
+	gitWebhook := &redhatcopv1alpha1.GitWebhook{}
+	gitWebhook.Spec = redhatcopv1alpha1.GitWebhookSpec{
+		AzureDevOps:     &redhatcopv1alpha1.AzureDevOpsServerConfig{AzureDevOpsAPIServerURL: server.URL, Organization: "org"},
+		RepositoryOwner: "project",
+		RepositoryName:  "app",
+		WebhookURL:      "https://hooks.example.com/app",
+		Events:          []string{"git.push", "git.pullrequest.created"},
+		Active:          true,
+	}
+
+	webhooktest.RunLifecycle(t, server.Server, func() redhatcopv1alpha1.WebHook { return newTestWebHook(t, server, gitWebhook) },
+		// one subscription per event
+		webhooktest.Step{
+			Name:      "create",
+			Mutations: []string{"POST " + testSubscriptionsPath, "POST " + testSubscriptionsPath},
+			Check: func(t *testing.T, webHook redhatcopv1alpha1.WebHook) {
+				if eventTypes := server.eventTypes(); !reflect.DeepEqual(eventTypes, []string{"git.pullrequest.created", "git.push"}) {
+					t.Fatalf("create: subscribed events = %v", eventTypes)
+				}
+				if applied := webHook.(*AzureDevOpsWebHook).AppliedWebHook(); applied == nil || applied.RepositoryID != "repo-id" || !reflect.DeepEqual(applied.Events, []string{"git.pullrequest.created", "git.push"}) {
+					t.Fatalf("create: applied webhook = %+v, want repository repo-id", applied)
+				}
+			},
+		},
+		// the subscriptions of the other repositories and urls are ignored
+		webhooktest.Step{
+			Name: "resync",
+			Check: func(t *testing.T, _ redhatcopv1alpha1.WebHook) {
+				if equivalent, err := newTestWebHook(t, server, gitWebhook).IsEquivalent(context.TODO()); err != nil || !equivalent {
+					t.Fatalf("resync: IsEquivalent() = %v, %v, want true", equivalent, err)
+				}
+			},
+		},
+		// the subscription of the removed event is deleted
+		webhooktest.Step{
+			Name: "update",
+			Change: func() {
+				gitWebhook.Spec.Events = []string{"git.push", "git.pullrequest.updated"}
+				gitWebhook.Spec.Active = false
+			},
+			Mutations: []string{"DELETE " + testSubscriptionsPath + "/sub-2", "POST " + testSubscriptionsPath, "PUT " + testSubscriptionsPath + "/sub-1"},
+			Check: func(t *testing.T, _ redhatcopv1alpha1.WebHook) {
+				if eventTypes := server.eventTypes(); !reflect.DeepEqual(eventTypes, []string{"git.pullrequest.updated", "git.push"}) {
+					t.Fatalf("update: subscribed events = %v", eventTypes)
+				}
+				for _, subscription := range server.subscriptions {
+					if subscription.ID == "sub-1" && subscription.Status != disabledByUser {
+						t.Fatalf("update: status = %q, want %q", subscription.Status, disabledByUser)
+					}
+				}
+			},
+		},
+		// the subscriptions of the other repositories and urls are left alone
+		webhooktest.Step{
+			Name:      "delete",
+			Delete:    true,
+			Mutations: []string{"DELETE " + testSubscriptionsPath + "/sub-1", "DELETE " + testSubscriptionsPath + "/sub-3"},
+			Check: func(t *testing.T, _ redhatcopv1alpha1.WebHook) {
+				if len(server.subscriptions) != 2 || server.subscriptions[0].ID != "other-repo" || server.subscriptions[1].ID != "other-url" {
+					t.Fatalf("delete: unexpected remaining subscriptions %+v", server.subscriptions)
+				}
+			},
+		},
+	)
+}
@@ -0,0 +1,50 @@
+package bitbucket
+
+import (
+	redhatcopv1alpha1 "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1"
+)
+
+func init() {
+	redhatcopv1alpha1.RegisterProvider(&provider{})
+}
+
+// provider registers bitbucket cloud with the GitWebhook controller
+type provider struct{}
+
+func (p *provider) Name() string {
+	return "bitbucket"
+}
+
+func (p *provider) ServerConfig(spec *redhatcopv1alpha1.GitWebhookSpec) redhatcopv1alpha1.ServerConfig {
+	if spec.Bitbucket == nil {
+		return nil
+	}
+	return spec.Bitbucket
+}
+
+func (p *provider) NewWebHook(gitWebhook *redhatcopv1alpha1.GitWebhook) redhatcopv1alpha1.WebHook {
+	return FromGitWebhook(gitWebhook)
+}
+
+func (p *provider) CredentialKeys() [][]string {
+	return [][]string{{"token"}}
+}
+
+func (p *provider) Events() []string {
+	return nil
+}
+
+func (p *provider) CanonicalEvents() map[string][]string {
+	return map[string][]string{
+		"push":         {"repo:push"},
+		"tag":          {"repo:push"},
+		"pull_request": {"pullrequest:created", "pullrequest:updated", "pullrequest:fulfilled", "pullrequest:rejected"},
+		"issue":        {"issue:created", "issue:updated"},
+		"comment":      {"pullrequest:comment_created", "issue:comment_created", "repo:commit_comment_created"},
+		"pipeline":     {"repo:commit_status_created", "repo:commit_status_updated"},
+	}
+}
+
+func (p *provider) SupportsOwnerWebHooks() bool {
+	return false
+}
@@ -0,0 +1,314 @@
+package bitbucket
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1"
+	"github.com/redhat-cop/gitwebhook-operator/api/v1alpha1/rest"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+type BitbucketWebHook struct {
+	gitWebhook *redhatcopv1alpha1.GitWebhook
+	client     *rest.Client
+	applied    *redhatcopv1alpha1.AppliedWebHook
+	redhatcopv1alpha1.HookAdoption
+}
+
+// hook is a bitbucket cloud repository webhook, see https://developer.atlassian.com/cloud/bitbucket/rest/api-group-repositories/#api-repositories-workspace-repo-slug-hooks-post
+type hook struct {
+	UUID                 string   `json:"uuid,omitempty"`
+	URL                  string   `json:"url"`
+	Description          string   `json:"description"`
+	Active               bool     `json:"active"`
+	SkipCertVerification bool     `json:"skip_cert_verification"`
+	Events               []string `json:"events"`
+	Secret               *string  `json:"secret,omitempty"`
+}
+
+// repository is the part of a bitbucket repository that is read
+type repository struct {
+	UUID string `json:"uuid"`
+}
+
+type hookPage struct {
+	Values []*hook `json:"values"`
+	Next   string  `json:"next"`
+}
+
+var _ redhatcopv1alpha1.WebHook = &BitbucketWebHook{}
+var _ redhatcopv1alpha1.DriftDetector = &BitbucketWebHook{}
+var _ redhatcopv1alpha1.AppliedWebHookReporter = &BitbucketWebHook{}
+var _ redhatcopv1alpha1.HookAdopter = &BitbucketWebHook{}
+
+func FromGitWebhook(gitwebhook *redhatcopv1alpha1.GitWebhook) *BitbucketWebHook {
+	return &BitbucketWebHook{
+		gitWebhook: gitwebhook,
+	}
+}
+
+func (m *BitbucketWebHook) Reconcile(ctx context.Context) error {
+	return m.reconcile(ctx)
+}
+
+func (m *BitbucketWebHook) Delete(ctx context.Context) error {
+	return m.deleteIfExists(ctx)
+}
+
+func (m *BitbucketWebHook) IsEquivalent(ctx context.Context) (bool, error) {
+	return m.isEquivalent(ctx)
+}
+
+func (m *BitbucketWebHook) AppliedWebHook() *redhatcopv1alpha1.AppliedWebHook {
+	return m.applied
+}
+
+func (m *BitbucketWebHook) repositoryPath() string {
+	return "repositories/" + url.PathEscape(m.gitWebhook.Spec.RepositoryOwner) + "/" + url.PathEscape(m.gitWebhook.Spec.RepositoryName)
+}
+
+func (m *BitbucketWebHook) hooksPath() string {
+	return m.repositoryPath() + "/hooks"
+}
+
+func (m *BitbucketWebHook) toWebhook(ctx context.Context) (*hook, error) {
+	log := log.FromContext(ctx)
+	secret, err := m.gitWebhook.GetWebhookSecret(ctx)
+	if err != nil {
+		log.Error(err, "unable to retrieve webhook secret")
+		return nil, err
+	}
+	webhookURL, err := m.gitWebhook.GetWebhookURL(ctx)
+	if err != nil {
+		log.Error(err, "unable to retrieve webhook url")
+		return nil, err
+	}
+	events, err := m.gitWebhook.GetNativeEvents()
+	if err != nil {
+		log.Error(err, "unable to translate events")
+		return nil, err
+	}
+	sort.Strings(events)
+	hook := hook{
+		URL:                  webhookURL,
+		Description:          m.gitWebhook.GetNamespace() + "/" + m.gitWebhook.GetName(),
+		Active:               m.gitWebhook.Spec.Active,
+		SkipCertVerification: m.gitWebhook.Spec.InsecureSSL,
+		Events:               events,
+	}
+	if secret != "" {
+		hook.Secret = &secret
+	}
+	return &hook, nil
+}
+
+func (m *BitbucketWebHook) getClient(ctx context.Context) (*rest.Client, error) {
+	if m.client != nil {
+		return m.client, nil
+	}
+	log := log.FromContext(ctx)
+	secret, err := m.gitWebhook.GetGitCredentialSecret(ctx, m.gitWebhook.Spec.Bitbucket)
+	if err != nil {
+		log.Error(err, "Unable to retrieve bitbucket credential", "secret", m.gitWebhook.Spec.Bitbucket.GitServerCredentials.Name)
+		return nil, err
+	}
+	token, found := secret.Data["token"]
+	if !found {
+		return nil, errors.New("\"token\" key not found in secret " + secret.Name)
+	}
+	// app passwords use basic authentication, access tokens are sent as bearer tokens
+	authorize := rest.BearerToken(string(token))
+	if username, found := secret.Data["username"]; found {
+		authorize = rest.BasicAuth(string(username), string(token))
+	}
+	m.client, err = rest.NewClient(m.gitWebhook.Spec.Bitbucket.BitbucketAPIServerURL, authorize)
+	if err != nil {
+		log.Error(err, "Unable to parse bitbucket url", "url", m.gitWebhook.Spec.Bitbucket.BitbucketAPIServerURL)
+		return nil, err
+	}
+	return m.client, nil
+}
+
+func (m *BitbucketWebHook) getHook(ctx context.Context) (*hook, bool, error) {
+	log := log.FromContext(ctx)
+	client, err := m.getClient(ctx)
+	if err != nil {
+		log.Error(err, "unable to create bitbucket client")
+		return nil, false, err
+	}
+	webhookURL, err := m.gitWebhook.GetWebhookURL(ctx)
+	if err != nil {
+		log.Error(err, "unable to retrieve webhook url")
+		return nil, false, err
+	}
+	finder := redhatcopv1alpha1.NewHookFinder[*hook](m.gitWebhook, &m.HookAdoption, webhookURL)
+	next := m.hooksPath() + "?pagelen=100"
+	for next != "" {
+		page := hookPage{}
+		_, err := client.Do(ctx, http.MethodGet, next, nil, &page)
+		if err != nil {
+			log.Error(err, "unable to list hooks", "for repo", m.gitWebhook.Spec.RepositoryOwner+"/"+m.gitWebhook.Spec.RepositoryName)
+			return nil, false, err
+		}
+		for _, hook := range page.Values {
+			if finder.Owned(hook, hook.UUID, hook.URL) {
+				return hook, true, nil
+			}
+		}
+		next = page.Next
+	}
+	return finder.Claim()
+}
+
+func (m *BitbucketWebHook) isEquivalent(ctx context.Context) (bool, error) {
+	log := log.FromContext(ctx)
+	desiredHook, err := m.toWebhook(ctx)
+	if err != nil {
+		log.Error(err, "unable to convert to bitbucket webhook")
+		return false, err
+	}
+	actualHook, found, err := m.getHook(ctx)
+	if err != nil {
+		log.Error(err, "error while retrieving current webhook")
+		return false, err
+	}
+	if !found {
+		return false, nil
+	}
+	secret, err := m.gitWebhook.GetWebhookSecret(ctx)
+	if err != nil {
+		log.Error(err, "unable to retrieve webhook secret")
+		return false, err
+	}
+	// the secret is not returned by the git server, a changed secret is detected with the fingerprint of the applied secret
+	if !m.gitWebhook.IsWebhookSecretApplied(secret) {
+		return false, nil
+	}
+	actualHook.UUID = ""
+	sort.Strings(actualHook.Events)
+	// the secret is never returned by bitbucket
+	desiredHook.Secret = nil
+	return reflect.DeepEqual(desiredHook, actualHook), nil
+}
+
+func (m *BitbucketWebHook) reconcile(ctx context.Context) error {
+	log := log.FromContext(ctx)
+	equivalent, err := m.isEquivalent(ctx)
+	if err != nil {
+		log.Error(err, "unable to determine if desired state is equal to actual state")
+		return err
+	}
+	if !equivalent {
+		err = m.createOrUpdateWebhook(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	return m.recordAppliedWebHook(ctx)
+}
+
+// recordAppliedWebHook records the webhook sent to or found in sync on bitbucket
+func (m *BitbucketWebHook) recordAppliedWebHook(ctx context.Context) error {
+	log := log.FromContext(ctx)
+	hook, err := m.toWebhook(ctx)
+	if err != nil {
+		log.Error(err, "error to convert to bitbucket hook")
+		return err
+	}
+	repositoryID, err := m.getRepositoryID(ctx)
+	if err != nil {
+		log.Error(err, "unable to retrieve repository id", "repository", m.gitWebhook.Spec.RepositoryOwner+"/"+m.gitWebhook.Spec.RepositoryName)
+		return err
+	}
+	m.applied = &redhatcopv1alpha1.AppliedWebHook{
+		RepositoryID: repositoryID,
+		Events:       hook.Events,
+		Settings: redhatcopv1alpha1.AppliedWebhookSettings{
+			Active:      &hook.Active,
+			InsecureSSL: &hook.SkipCertVerification,
+		},
+	}
+	return nil
+}
+
+// getRepositoryID returns the uuid of the repository, the repository of a GitWebhook cannot be changed so the id recorded in the status is reused
+func (m *BitbucketWebHook) getRepositoryID(ctx context.Context) (string, error) {
+	if m.gitWebhook.Status.RepositoryID != "" {
+		return m.gitWebhook.Status.RepositoryID, nil
+	}
+	client, err := m.getClient(ctx)
+	if err != nil {
+		return "", err
+	}
+	repository := &repository{}
+	_, err = client.Do(ctx, http.MethodGet, m.repositoryPath(), nil, repository)
+	if err != nil {
+		return "", err
+	}
+	return repository.UUID, nil
+}
+
+func (m *BitbucketWebHook) createOrUpdateWebhook(ctx context.Context) error {
+	log := log.FromContext(ctx)
+	actualHook, found, err := m.getHook(ctx)
+	if err != nil {
+		log.Error(err, "error while retrieving webhook")
+		return err
+	}
+	client, err := m.getClient(ctx)
+	if err != nil {
+		log.Error(err, "error get bitbucket client")
+		return err
+	}
+	newHook, err := m.toWebhook(ctx)
+	if err != nil {
+		log.Error(err, "error to convert to bitbucket hook")
+		return err
+	}
+	if !found {
+		//we need to create
+		createdHook := &hook{}
+		_, err = client.Do(ctx, http.MethodPost, m.hooksPath(), newHook, createdHook)
+		if err != nil {
+			log.Error(err, "unable to create new hook")
+			return err
+		}
+		m.gitWebhook.SetOwnedHook(createdHook.UUID)
+	} else {
+		//we need to update
+		_, err = client.Do(ctx, http.MethodPut, m.hooksPath()+"/"+url.PathEscape(actualHook.UUID), newHook, nil)
+		if err != nil {
+			log.Error(err, "unable to update bitbucket webhook")
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *BitbucketWebHook) deleteIfExists(ctx context.Context) error {
+	log := log.FromContext(ctx)
+	hook, found, err := m.getHook(ctx)
+	if err != nil {
+		log.Error(err, "error while retrieving webhook")
+		return err
+	}
+	if !found {
+		return nil
+	}
+	client, err := m.getClient(ctx)
+	if err != nil {
+		log.Error(err, "error get bitbucket client")
+		return err
+	}
+	_, err = client.Do(ctx, http.MethodDelete, m.hooksPath()+"/"+url.PathEscape(hook.UUID), nil, nil)
+	if err != nil && !rest.IsNotFound(err) {
+		log.Error(err, "unable to delete webhook")
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package bitbucket
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1"
+	"github.com/redhat-cop/gitwebhook-operator/api/v1alpha1/rest"
+	"github.com/redhat-cop/gitwebhook-operator/api/v1alpha1/webhooktest"
+)
+
+const testHooksPath = "/repositories/team/app/hooks"
+
+// fakeServer serves the hooks of a repository, one hook per page so that the listing is paginated
+type fakeServer struct {
+	*webhooktest.Server
+	hooks    []*hook
+	lastUUID int
+}
+
+func newFakeServer(t *testing.T, hooks ...*hook) *fakeServer {
+	s := &fakeServer{hooks: hooks}
+	s.Server = webhooktest.NewServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == strings.TrimSuffix(testHooksPath, "/hooks"):
+			json.NewEncoder(w).Encode(&repository{UUID: "{repository}"})
+		case r.Method == http.MethodGet && r.URL.Path == testHooksPath:
+			page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+			if page == 0 {
+				page = 1
+			}
+			result := hookPage{Values: []*hook{}}
+			if page <= len(s.hooks) {
+				result.Values = append(result.Values, s.hooks[page-1])
+			}
+			if page < len(s.hooks) {
+				result.Next = s.URL + testHooksPath + "?pagelen=1&page=" + strconv.Itoa(page+1)
+			}
+			json.NewEncoder(w).Encode(result)
+		case r.Method == http.MethodPost && r.URL.Path == testHooksPath:
+			created := &hook{}
+			json.NewDecoder(r.Body).Decode(created)
+			s.lastUUID++
+			created.UUID = "{" + strconv.Itoa(s.lastUUID) + "}"
+			created.Secret = nil
+			s.hooks = append(s.hooks, created)
+			json.NewEncoder(w).Encode(created)
+		case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, testHooksPath+"/"):
+			for i, existing := range s.hooks {
+				if existing.UUID == strings.TrimPrefix(r.URL.Path, testHooksPath+"/") {
+					updated := &hook{}
+					json.NewDecoder(r.Body).Decode(updated)
+					updated.UUID = existing.UUID
+					updated.Secret = nil
+					s.hooks[i] = updated
+					json.NewEncoder(w).Encode(updated)
+					return
+				}
+			}
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, testHooksPath+"/"):
+			for i, existing := range s.hooks {
+				if existing.UUID == strings.TrimPrefix(r.URL.Path, testHooksPath+"/") {
+					s.hooks = append(s.hooks[:i], s.hooks[i+1:]...)
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+			}
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			webhooktest.UnexpectedRequest(t, w, r)
+		}
+	})
+	return s
+}
+
+func newTestWebHook(t *testing.T, server *fakeServer, gitWebhook *redhatcopv1alpha1.GitWebhook) *BitbucketWebHook {
+	client, err := rest.NewClient(server.URL, rest.BearerToken("token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &BitbucketWebHook{gitWebhook: gitWebhook, client: client}
+}
+
+func TestBitbucketWebHookLifecycle(t *testing.T) {
+	server := newFakeServer(t,
+		&hook{UUID: "{other-1}", URL: "https://other.example.com/1", Events: []string{"repo:push"}},
+		&hook{UUID: "{other-2}", URL: "https://other.example.com/2", Events: []string{"repo:push"}},
+	)
+	gitWebhook := &redhatcopv1alpha1.GitWebhook{}
+	gitWebhook.Name = "hook"
+	gitWebhook.Namespace = "default"
+	gitWebhook.Spec = redhatcopv1alpha1.GitWebhookSpec{
+		Bitbucket:       &redhatcopv1alpha1.BitbucketServerConfig{BitbucketAPIServerURL: server.URL},
+		RepositoryOwner: "team",
+		RepositoryName:  "app",
+		WebhookURL:      "https://hooks.example.com/app",
+		Events:          []string{"repo:push"},
+		Active:          true,
+	}
+
+	webhooktest.RunLifecycle(t, server.Server, func() redhatcopv1alpha1.WebHook { return newTestWebHook(t, server, gitWebhook) },
+		// the hooks of the other receivers are listed over several pages
+		webhooktest.Step{
+			Name:      "create",
+			Mutations: []string{"POST " + testHooksPath},
+			Check: func(t *testing.T, _ redhatcopv1alpha1.WebHook) {
+				if len(server.hooks) != 3 || server.hooks[2].URL != "https://hooks.example.com/app" || server.hooks[2].Description != "default/hook" {
+					t.Fatalf("create: hook not created as expected: %+v", server.hooks)
+				}
+				if gitWebhook.Status.HookID != "{1}" {
+					t.Fatalf("create: hookID = %q, want {1}", gitWebhook.Status.HookID)
+				}
+			},
+		},
+		// the owned hook is found on the last page
+		webhooktest.Step{Name: "resync"},
+		// the braces of the uuid are escaped in the path of the hook
+		webhooktest.Step{
+			Name:      "update",
+			Change:    func() { gitWebhook.Spec.Events = []string{"repo:push", "pullrequest:created"} },
+			Mutations: []string{"PUT " + testHooksPath + "/%7B1%7D"},
+			Check: func(t *testing.T, _ redhatcopv1alpha1.WebHook) {
+				if len(server.hooks) != 3 || len(server.hooks[2].Events) != 2 || server.hooks[2].UUID != "{1}" {
+					t.Fatalf("update: hook not updated as expected: %+v", server.hooks[2])
+				}
+			},
+		},
+		// the hooks of the other receivers are left alone
+		webhooktest.Step{
+			Name:      "delete",
+			Delete:    true,
+			Mutations: []string{"DELETE " + testHooksPath + "/%7B1%7D"},
+			Check: func(t *testing.T, _ redhatcopv1alpha1.WebHook) {
+				if len(server.hooks) != 2 || server.hooks[0].UUID != "{other-1}" || server.hooks[1].UUID != "{other-2}" {
+					t.Fatalf("delete: unexpected remaining hooks %+v", server.hooks)
+				}
+			},
+		},
+	)
+}
@@ -0,0 +1,48 @@
+package bitbucketdatacenter
+
+import (
+	redhatcopv1alpha1 "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1"
+)
+
+func init() {
+	redhatcopv1alpha1.RegisterProvider(&provider{})
+}
+
+// provider registers bitbucket data center with the GitWebhook controller
+type provider struct{}
+
+func (p *provider) Name() string {
+	return "bitbucketDataCenter"
+}
+
+func (p *provider) ServerConfig(spec *redhatcopv1alpha1.GitWebhookSpec) redhatcopv1alpha1.ServerConfig {
+	if spec.BitbucketDataCenter == nil {
+		return nil
+	}
+	return spec.BitbucketDataCenter
+}
+
+func (p *provider) NewWebHook(gitWebhook *redhatcopv1alpha1.GitWebhook) redhatcopv1alpha1.WebHook {
+	return FromGitWebhook(gitWebhook)
+}
+
+func (p *provider) CredentialKeys() [][]string {
+	return [][]string{{"token"}}
+}
+
+func (p *provider) Events() []string {
+	return nil
+}
+
+func (p *provider) CanonicalEvents() map[string][]string {
+	return map[string][]string{
+		"push":         {"repo:refs_changed"},
+		"tag":          {"repo:refs_changed"},
+		"pull_request": {"pr:opened", "pr:from_ref_updated", "pr:merged", "pr:declined"},
+		"comment":      {"pr:comment:added", "repo:comment:added"},
+	}
+}
+
+func (p *provider) SupportsOwnerWebHooks() bool {
+	return false
+}
@@ -0,0 +1,325 @@
+package bitbucketdatacenter
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1"
+	"github.com/redhat-cop/gitwebhook-operator/api/v1alpha1/rest"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+type BitbucketDataCenterWebHook struct {
+	gitWebhook *redhatcopv1alpha1.GitWebhook
+	client     *rest.Client
+	applied    *redhatcopv1alpha1.AppliedWebHook
+	redhatcopv1alpha1.HookAdoption
+}
+
+// hook is a bitbucket data center repository webhook, see https://developer.atlassian.com/server/bitbucket/rest/v811/api-group-repository/#api-api-latest-projects-projectkey-repos-repositoryslug-webhooks-post
+type hook struct {
+	ID                      int               `json:"id,omitempty"`
+	Name                    string            `json:"name"`
+	URL                     string            `json:"url"`
+	Active                  bool              `json:"active"`
+	SSLVerificationRequired bool              `json:"sslVerificationRequired"`
+	Events                  []string          `json:"events"`
+	Configuration           map[string]string `json:"configuration,omitempty"`
+}
+
+// repository is the part of a bitbucket data center repository that is read
+type repository struct {
+	ID int `json:"id"`
+}
+
+type hookPage struct {
+	Values        []*hook `json:"values"`
+	IsLastPage    bool    `json:"isLastPage"`
+	NextPageStart int     `json:"nextPageStart"`
+}
+
+var _ redhatcopv1alpha1.WebHook = &BitbucketDataCenterWebHook{}
+var _ redhatcopv1alpha1.DriftDetector = &BitbucketDataCenterWebHook{}
+var _ redhatcopv1alpha1.AppliedWebHookReporter = &BitbucketDataCenterWebHook{}
+var _ redhatcopv1alpha1.HookAdopter = &BitbucketDataCenterWebHook{}
+
+func FromGitWebhook(gitwebhook *redhatcopv1alpha1.GitWebhook) *BitbucketDataCenterWebHook {
+	return &BitbucketDataCenterWebHook{
+		gitWebhook: gitwebhook,
+	}
+}
+
+func (m *BitbucketDataCenterWebHook) Reconcile(ctx context.Context) error {
+	return m.reconcile(ctx)
+}
+
+func (m *BitbucketDataCenterWebHook) Delete(ctx context.Context) error {
+	return m.deleteIfExists(ctx)
+}
+
+func (m *BitbucketDataCenterWebHook) IsEquivalent(ctx context.Context) (bool, error) {
+	return m.isEquivalent(ctx)
+}
+
+func (m *BitbucketDataCenterWebHook) AppliedWebHook() *redhatcopv1alpha1.AppliedWebHook {
+	return m.applied
+}
+
+// RepositoryOwner is the project key and RepositoryName the repository slug
+func (m *BitbucketDataCenterWebHook) repositoryPath() string {
+	return "rest/api/1.0/projects/" + url.PathEscape(m.gitWebhook.Spec.RepositoryOwner) + "/repos/" + url.PathEscape(m.gitWebhook.Spec.RepositoryName)
+}
+
+func (m *BitbucketDataCenterWebHook) webhooksPath() string {
+	return m.repositoryPath() + "/webhooks"
+}
+
+func (m *BitbucketDataCenterWebHook) toWebhook(ctx context.Context) (*hook, error) {
+	log := log.FromContext(ctx)
+	secret, err := m.gitWebhook.GetWebhookSecret(ctx)
+	if err != nil {
+		log.Error(err, "unable to retrieve webhook secret")
+		return nil, err
+	}
+	webhookURL, err := m.gitWebhook.GetWebhookURL(ctx)
+	if err != nil {
+		log.Error(err, "unable to retrieve webhook url")
+		return nil, err
+	}
+	events, err := m.gitWebhook.GetNativeEvents()
+	if err != nil {
+		log.Error(err, "unable to translate events")
+		return nil, err
+	}
+	sort.Strings(events)
+	hook := hook{
+		Name:                    m.gitWebhook.GetNamespace() + "/" + m.gitWebhook.GetName(),
+		URL:                     webhookURL,
+		Active:                  m.gitWebhook.Spec.Active,
+		SSLVerificationRequired: !m.gitWebhook.Spec.InsecureSSL,
+		Events:                  events,
+	}
+	if secret != "" {
+		hook.Configuration = map[string]string{
+			"secret": secret,
+		}
+	}
+	return &hook, nil
+}
+
+func (m *BitbucketDataCenterWebHook) getClient(ctx context.Context) (*rest.Client, error) {
+	if m.client != nil {
+		return m.client, nil
+	}
+	log := log.FromContext(ctx)
+	secret, err := m.gitWebhook.GetGitCredentialSecret(ctx, m.gitWebhook.Spec.BitbucketDataCenter)
+	if err != nil {
+		log.Error(err, "Unable to retrieve bitbucket data center credential", "secret", m.gitWebhook.Spec.BitbucketDataCenter.GitServerCredentials.Name)
+		return nil, err
+	}
+	token, found := secret.Data["token"]
+	if !found {
+		return nil, errors.New("\"token\" key not found in secret " + secret.Name)
+	}
+	// http access tokens are sent as bearer tokens, passwords need basic authentication
+	authorize := rest.BearerToken(string(token))
+	if username, found := secret.Data["username"]; found {
+		authorize = rest.BasicAuth(string(username), string(token))
+	}
+	m.client, err = rest.NewClient(m.gitWebhook.Spec.BitbucketDataCenter.BitbucketDataCenterAPIServerURL, authorize)
+	if err != nil {
+		log.Error(err, "Unable to parse bitbucket data center url", "url", m.gitWebhook.Spec.BitbucketDataCenter.BitbucketDataCenterAPIServerURL)
+		return nil, err
+	}
+	return m.client, nil
+}
+
+func (m *BitbucketDataCenterWebHook) getHook(ctx context.Context) (*hook, bool, error) {
+	log := log.FromContext(ctx)
+	client, err := m.getClient(ctx)
+	if err != nil {
+		log.Error(err, "unable to create bitbucket data center client")
+		return nil, false, err
+	}
+	webhookURL, err := m.gitWebhook.GetWebhookURL(ctx)
+	if err != nil {
+		log.Error(err, "unable to retrieve webhook url")
+		return nil, false, err
+	}
+	finder := redhatcopv1alpha1.NewHookFinder[*hook](m.gitWebhook, &m.HookAdoption, webhookURL)
+	start := 0
+	for {
+		page := hookPage{}
+		_, err := client.Do(ctx, http.MethodGet, m.webhooksPath()+"?limit=100&start="+strconv.Itoa(start), nil, &page)
+		if err != nil {
+			log.Error(err, "unable to list hooks", "for repo", m.gitWebhook.Spec.RepositoryOwner+"/"+m.gitWebhook.Spec.RepositoryName)
+			return nil, false, err
+		}
+		for _, hook := range page.Values {
+			if finder.Owned(hook, strconv.Itoa(hook.ID), hook.URL) {
+				return hook, true, nil
+			}
+		}
+		// a next page that does not move forward would loop forever
+		if page.IsLastPage || page.NextPageStart <= start {
+			break
+		}
+		start = page.NextPageStart
+	}
+	return finder.Claim()
+}
+
+func (m *BitbucketDataCenterWebHook) isEquivalent(ctx context.Context) (bool, error) {
+	log := log.FromContext(ctx)
+	desiredHook, err := m.toWebhook(ctx)
+	if err != nil {
+		log.Error(err, "unable to convert to bitbucket data center webhook")
+		return false, err
+	}
+	actualHook, found, err := m.getHook(ctx)
+	if err != nil {
+		log.Error(err, "error while retrieving current webhook")
+		return false, err
+	}
+	if !found {
+		return false, nil
+	}
+	secret, err := m.gitWebhook.GetWebhookSecret(ctx)
+	if err != nil {
+		log.Error(err, "unable to retrieve webhook secret")
+		return false, err
+	}
+	// the secret is not returned by the git server, a changed secret is detected with the fingerprint of the applied secret
+	if !m.gitWebhook.IsWebhookSecretApplied(secret) {
+		return false, nil
+	}
+	actualHook.ID = 0
+	sort.Strings(actualHook.Events)
+	// the secret is not reliably returned by the server
+	actualHook.Configuration = nil
+	desiredHook.Configuration = nil
+	return reflect.DeepEqual(desiredHook, actualHook), nil
+}
+
+func (m *BitbucketDataCenterWebHook) reconcile(ctx context.Context) error {
+	log := log.FromContext(ctx)
+	equivalent, err := m.isEquivalent(ctx)
+	if err != nil {
+		log.Error(err, "unable to determine if desired state is equal to actual state")
+		return err
+	}
+	if !equivalent {
+		err = m.createOrUpdateWebhook(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	return m.recordAppliedWebHook(ctx)
+}
+
+// recordAppliedWebHook records the webhook sent to or found in sync on bitbucket data center
+func (m *BitbucketDataCenterWebHook) recordAppliedWebHook(ctx context.Context) error {
+	log := log.FromContext(ctx)
+	hook, err := m.toWebhook(ctx)
+	if err != nil {
+		log.Error(err, "error to convert to bitbucket data center hook")
+		return err
+	}
+	repositoryID, err := m.getRepositoryID(ctx)
+	if err != nil {
+		log.Error(err, "unable to retrieve repository id", "repository", m.gitWebhook.Spec.RepositoryOwner+"/"+m.gitWebhook.Spec.RepositoryName)
+		return err
+	}
+	insecureSSL := !hook.SSLVerificationRequired
+	m.applied = &redhatcopv1alpha1.AppliedWebHook{
+		RepositoryID: repositoryID,
+		Events:       hook.Events,
+		Settings: redhatcopv1alpha1.AppliedWebhookSettings{
+			Active:      &hook.Active,
+			InsecureSSL: &insecureSSL,
+		},
+	}
+	return nil
+}
+
+// getRepositoryID returns the id of the repository, the repository of a GitWebhook cannot be changed so the id recorded in the status is reused
+func (m *BitbucketDataCenterWebHook) getRepositoryID(ctx context.Context) (string, error) {
+	if m.gitWebhook.Status.RepositoryID != "" {
+		return m.gitWebhook.Status.RepositoryID, nil
+	}
+	client, err := m.getClient(ctx)
+	if err != nil {
+		return "", err
+	}
+	repository := &repository{}
+	_, err = client.Do(ctx, http.MethodGet, m.repositoryPath(), nil, repository)
+	if err != nil {
+		return "", err
+	}
+	return strconv.Itoa(repository.ID), nil
+}
+
+func (m *BitbucketDataCenterWebHook) createOrUpdateWebhook(ctx context.Context) error {
+	log := log.FromContext(ctx)
+	actualHook, found, err := m.getHook(ctx)
+	if err != nil {
+		log.Error(err, "error while retrieving webhook")
+		return err
+	}
+	client, err := m.getClient(ctx)
+	if err != nil {
+		log.Error(err, "error get bitbucket data center client")
+		return err
+	}
+	newHook, err := m.toWebhook(ctx)
+	if err != nil {
+		log.Error(err, "error to convert to bitbucket data center hook")
+		return err
+	}
+	if !found {
+		//we need to create
+		createdHook := &hook{}
+		_, err = client.Do(ctx, http.MethodPost, m.webhooksPath(), newHook, createdHook)
+		if err != nil {
+			log.Error(err, "unable to create new hook")
+			return err
+		}
+		m.gitWebhook.SetOwnedHook(strconv.Itoa(createdHook.ID))
+	} else {
+		//we need to update
+		_, err = client.Do(ctx, http.MethodPut, m.webhooksPath()+"/"+strconv.Itoa(actualHook.ID), newHook, nil)
+		if err != nil {
+			log.Error(err, "unable to update bitbucket data center webhook")
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *BitbucketDataCenterWebHook) deleteIfExists(ctx context.Context) error {
+	log := log.FromContext(ctx)
+	hook, found, err := m.getHook(ctx)
+	if err != nil {
+		log.Error(err, "error while retrieving webhook")
+		return err
+	}
+	if !found {
+		return nil
+	}
+	client, err := m.getClient(ctx)
+	if err != nil {
+		log.Error(err, "error get bitbucket data center client")
+		return err
+	}
+	_, err = client.Do(ctx, http.MethodDelete, m.webhooksPath()+"/"+strconv.Itoa(hook.ID), nil, nil)
+	if err != nil && !rest.IsNotFound(err) {
+		log.Error(err, "unable to delete webhook")
+		return err
+	}
+	return nil
+}
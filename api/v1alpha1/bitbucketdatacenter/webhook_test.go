@@ -0,0 +1,164 @@
+package bitbucketdatacenter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1"
+	"github.com/redhat-cop/gitwebhook-operator/api/v1alpha1/rest"
+	"github.com/redhat-cop/gitwebhook-operator/api/v1alpha1/webhooktest"
+)
+
+const testWebhooksPath = "/rest/api/1.0/projects/TEAM/repos/app/webhooks"
+
+// fakeServer serves the webhooks of a repository, one webhook per page so that the listing is paginated
+type fakeServer struct {
+	*webhooktest.Server
+	hooks  []*hook
+	lastID int
+}
+
+func newFakeServer(t *testing.T, hooks ...*hook) *fakeServer {
+	s := &fakeServer{hooks: hooks, lastID: 100}
+	s.Server = webhooktest.NewServer(t, func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, testWebhooksPath+"/")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == strings.TrimSuffix(testWebhooksPath, "/webhooks"):
+			json.NewEncoder(w).Encode(&repository{ID: 42})
+		case r.Method == http.MethodGet && r.URL.Path == testWebhooksPath:
+			start, _ := strconv.Atoi(r.URL.Query().Get("start"))
+			page := hookPage{Values: []*hook{}, IsLastPage: start+1 >= len(s.hooks), NextPageStart: start + 1}
+			if start < len(s.hooks) {
+				page.Values = append(page.Values, s.hooks[start])
+			}
+			json.NewEncoder(w).Encode(page)
+		case r.Method == http.MethodPost && r.URL.Path == testWebhooksPath:
+			created := &hook{}
+			json.NewDecoder(r.Body).Decode(created)
+			s.lastID++
+			created.ID = s.lastID
+			s.hooks = append(s.hooks, created)
+			json.NewEncoder(w).Encode(created)
+		case r.Method == http.MethodPut && id != r.URL.Path:
+			for i, existing := range s.hooks {
+				if strconv.Itoa(existing.ID) == id {
+					updated := &hook{}
+					json.NewDecoder(r.Body).Decode(updated)
+					updated.ID = existing.ID
+					s.hooks[i] = updated
+					json.NewEncoder(w).Encode(updated)
+					return
+				}
+			}
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodDelete && id != r.URL.Path:
+			for i, existing := range s.hooks {
+				if strconv.Itoa(existing.ID) == id {
+					s.hooks = append(s.hooks[:i], s.hooks[i+1:]...)
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+			}
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			webhooktest.UnexpectedRequest(t, w, r)
+		}
+	})
+	return s
+}
+
+func newTestGitWebhook(serverURL string) *redhatcopv1alpha1.GitWebhook {
+	gitWebhook := &redhatcopv1alpha1.GitWebhook{}
+	gitWebhook.Name = "hook"
+	gitWebhook.Namespace = "default"
+	gitWebhook.Spec = redhatcopv1alpha1.GitWebhookSpec{
+		BitbucketDataCenter: &redhatcopv1alpha1.BitbucketDataCenterServerConfig{BitbucketDataCenterAPIServerURL: serverURL},
+		RepositoryOwner:     "TEAM",
+		RepositoryName:      "app",
+		WebhookURL:          "https://hooks.example.com/app",
+		Events:              []string{"repo:refs_changed"},
+		Active:              true,
+	}
+	return gitWebhook
+}
+
+func newTestWebHook(t *testing.T, serverURL string, gitWebhook *redhatcopv1alpha1.GitWebhook) *BitbucketDataCenterWebHook {
+	client, err := rest.NewClient(serverURL, rest.BearerToken("token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &BitbucketDataCenterWebHook{gitWebhook: gitWebhook, client: client}
+}
+
+func TestBitbucketDataCenterWebHookLifecycle(t *testing.T) {
+	server := newFakeServer(t,
+		&hook{ID: 1, Name: "other", URL: "https://other.example.com/1", Events: []string{"repo:refs_changed"}},
+		&hook{ID: 2, Name: "other", URL: "https://other.example.com/2", Events: []string{"repo:refs_changed"}},
+	)
+	gitWebhook := newTestGitWebhook(server.URL)
+
+	webhooktest.RunLifecycle(t, server.Server, func() redhatcopv1alpha1.WebHook { return newTestWebHook(t, server.URL, gitWebhook) },
+		// the webhooks of the other receivers are listed over several pages
+		webhooktest.Step{
+			Name:      "create",
+			Mutations: []string{"POST " + testWebhooksPath},
+			Check: func(t *testing.T, _ redhatcopv1alpha1.WebHook) {
+				if len(server.hooks) != 3 || server.hooks[2].URL != "https://hooks.example.com/app" || server.hooks[2].Name != "default/hook" || !server.hooks[2].SSLVerificationRequired {
+					t.Fatalf("create: webhook not created as expected: %+v", server.hooks)
+				}
+				if gitWebhook.Status.HookID != "101" {
+					t.Fatalf("create: hookID = %q, want 101", gitWebhook.Status.HookID)
+				}
+			},
+		},
+		// the owned webhook is found on the last page
+		webhooktest.Step{Name: "resync"},
+		webhooktest.Step{
+			Name:      "update",
+			Change:    func() { gitWebhook.Spec.Events = []string{"repo:refs_changed", "pr:opened"} },
+			Mutations: []string{"PUT " + testWebhooksPath + "/101"},
+			Check: func(t *testing.T, _ redhatcopv1alpha1.WebHook) {
+				if len(server.hooks) != 3 || len(server.hooks[2].Events) != 2 || server.hooks[2].ID != 101 {
+					t.Fatalf("update: webhook not updated as expected: %+v", server.hooks[2])
+				}
+			},
+		},
+		// the webhooks of the other receivers are left alone
+		webhooktest.Step{
+			Name:      "delete",
+			Delete:    true,
+			Mutations: []string{"DELETE " + testWebhooksPath + "/101"},
+			Check: func(t *testing.T, _ redhatcopv1alpha1.WebHook) {
+				if len(server.hooks) != 2 || server.hooks[0].ID != 1 || server.hooks[1].ID != 2 {
+					t.Fatalf("delete: unexpected remaining webhooks %+v", server.hooks)
+				}
+			},
+		},
+	)
+}
+
+func TestBitbucketDataCenterWebHookPageNotMovingForward(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		// the page is reported as the last one after a while, so that the test fails rather than hangs
+		json.NewEncoder(w).Encode(hookPage{
+			Values:        []*hook{{ID: 1, URL: "https://other.example.com/1"}},
+			IsLastPage:    requests > 10,
+			NextPageStart: 0,
+		})
+	}))
+	defer server.Close()
+	_, found, err := newTestWebHook(t, server.URL, newTestGitWebhook(server.URL)).getHook(context.TODO())
+	if err != nil || found {
+		t.Errorf("getHook() = %v, %v, want not found", found, err)
+	}
+	if requests != 1 {
+		t.Errorf("requests = %d, want 1", requests)
+	}
+}
@@ -0,0 +1,78 @@
+package gerrit
+
+import (
+	"errors"
+	"sort"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1"
+)
+
+func init() {
+	redhatcopv1alpha1.RegisterProvider(&provider{})
+}
+
+// provider registers gerrit with the GitWebhook controller
+type provider struct{}
+
+func (p *provider) Name() string {
+	return "gerrit"
+}
+
+func (p *provider) ServerConfig(spec *redhatcopv1alpha1.GitWebhookSpec) redhatcopv1alpha1.ServerConfig {
+	if spec.Gerrit == nil {
+		return nil
+	}
+	return spec.Gerrit
+}
+
+func (p *provider) NewWebHook(gitWebhook *redhatcopv1alpha1.GitWebhook) redhatcopv1alpha1.WebHook {
+	return FromGitWebhook(gitWebhook)
+}
+
+func (p *provider) CredentialKeys() [][]string {
+	return [][]string{{"username", "token"}}
+}
+
+func (p *provider) Events() []string {
+	catalog := []string{}
+	for event := range events {
+		catalog = append(catalog, event)
+	}
+	sort.Strings(catalog)
+	return catalog
+}
+
+func (p *provider) CanonicalEvents() map[string][]string {
+	return map[string][]string{
+		"push":         {"ref-updated"},
+		"tag":          {"ref-updated"},
+		"pull_request": {"patchset-created", "change-merged", "change-abandoned"},
+		"comment":      {"comment-added"},
+	}
+}
+
+func (p *provider) SupportsOwnerWebHooks() bool {
+	return false
+}
+
+// SupportsWebhookSecret the webhooks plugin does not support shared secrets
+func (p *provider) SupportsWebhookSecret() bool {
+	return false
+}
+
+// ValidateSpec rejects the fields that the webhooks plugin does not support
+func (p *provider) ValidateSpec(spec *redhatcopv1alpha1.GitWebhookSpec) error {
+	if spec.WebhookSecret.Name != "" || spec.WebhookSecret.Generate || spec.WebhookSecret.Rotation != nil {
+		return errors.New("webhookSecret is not supported by gerrit")
+	}
+	if !spec.Active {
+		return errors.New("active cannot be false for gerrit, remotes cannot be deactivated")
+	}
+	if spec.ContentType != "" && spec.ContentType != "json" {
+		return errors.New("contentType is not supported by gerrit, the payload is always json")
+	}
+	if spec.AdoptionPolicy != "" && spec.AdoptionPolicy != "Adopt" {
+		return errors.New("adoptionPolicy " + spec.AdoptionPolicy + " is not supported by gerrit, the remote named after the GitWebhook is always managed")
+	}
+	return nil
+}
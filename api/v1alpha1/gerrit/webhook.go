@@ -0,0 +1,257 @@
+package gerrit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1"
+	"github.com/redhat-cop/gitwebhook-operator/api/v1alpha1/rest"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// GerritWebHook manages a remote of the gerrit webhooks plugin, see https://gerrit.googlesource.com/plugins/webhooks/+/refs/heads/master/src/main/resources/Documentation/rest-api-config.md
+type GerritWebHook struct {
+	gitWebhook *redhatcopv1alpha1.GitWebhook
+	client     *rest.Client
+	applied    *redhatcopv1alpha1.AppliedWebHook
+}
+
+// remote is the RemoteInfo entity of the webhooks plugin
+type remote struct {
+	URL       string   `json:"url"`
+	Events    []string `json:"events"`
+	SSLVerify bool     `json:"ssl_verify"`
+}
+
+// events are the gerrit stream event types
+var events = map[string]bool{
+	"assignee-changed":      true,
+	"change-abandoned":      true,
+	"change-deleted":        true,
+	"change-merged":         true,
+	"change-restored":       true,
+	"comment-added":         true,
+	"hashtags-changed":      true,
+	"patchset-created":      true,
+	"private-state-changed": true,
+	"project-created":       true,
+	"ref-updated":           true,
+	"reviewer-added":        true,
+	"reviewer-deleted":      true,
+	"topic-changed":         true,
+	"vote-deleted":          true,
+	"wip-state-changed":     true,
+}
+
+var _ redhatcopv1alpha1.WebHook = &GerritWebHook{}
+var _ redhatcopv1alpha1.DriftDetector = &GerritWebHook{}
+var _ redhatcopv1alpha1.AppliedWebHookReporter = &GerritWebHook{}
+
+func FromGitWebhook(gitwebhook *redhatcopv1alpha1.GitWebhook) *GerritWebHook {
+	return &GerritWebHook{
+		gitWebhook: gitwebhook,
+	}
+}
+
+func (m *GerritWebHook) Reconcile(ctx context.Context) error {
+	return m.reconcile(ctx)
+}
+
+func (m *GerritWebHook) Delete(ctx context.Context) error {
+	return m.deleteIfExists(ctx)
+}
+
+func (m *GerritWebHook) IsEquivalent(ctx context.Context) (bool, error) {
+	return m.isEquivalent(ctx)
+}
+
+func (m *GerritWebHook) AppliedWebHook() *redhatcopv1alpha1.AppliedWebHook {
+	return m.applied
+}
+
+// project returns the gerrit project name, RepositoryOwner is the optional parent path of the project
+func (m *GerritWebHook) project() string {
+	if m.gitWebhook.Spec.RepositoryOwner == "" {
+		return m.gitWebhook.Spec.RepositoryName
+	}
+	return m.gitWebhook.Spec.RepositoryOwner + "/" + m.gitWebhook.Spec.RepositoryName
+}
+
+func (m *GerritWebHook) remoteName() string {
+	if m.gitWebhook.Spec.Gerrit.RemoteName != "" {
+		return m.gitWebhook.Spec.Gerrit.RemoteName
+	}
+	return m.gitWebhook.GetNamespace() + "-" + m.gitWebhook.GetName()
+}
+
+// the "a/" prefix selects the authenticated rest api
+func (m *GerritWebHook) remotePath() string {
+	return "a/config/server/webhooks~projects/" + url.PathEscape(m.project()) + "/remotes/" + url.PathEscape(m.remoteName())
+}
+
+func (m *GerritWebHook) toRemote(ctx context.Context) (*remote, error) {
+	webhookURL, err := m.gitWebhook.GetWebhookURL(ctx)
+	if err != nil {
+		return nil, err
+	}
+	remote := remote{
+		URL:       webhookURL,
+		SSLVerify: !m.gitWebhook.Spec.InsecureSSL,
+	}
+	nativeEvents, err := m.gitWebhook.GetNativeEvents()
+	if err != nil {
+		return nil, err
+	}
+	for _, event := range nativeEvents {
+		if !events[event] {
+			return nil, errors.New("unknown event type:" + event)
+		}
+		remote.Events = append(remote.Events, event)
+	}
+	sort.Strings(remote.Events)
+	return &remote, nil
+}
+
+func (m *GerritWebHook) getClient(ctx context.Context) (*rest.Client, error) {
+	if m.client != nil {
+		return m.client, nil
+	}
+	log := log.FromContext(ctx)
+	secret, err := m.gitWebhook.GetGitCredentialSecret(ctx, m.gitWebhook.Spec.Gerrit)
+	if err != nil {
+		log.Error(err, "Unable to retrieve gerrit credential", "secret", m.gitWebhook.Spec.Gerrit.GitServerCredentials.Name)
+		return nil, err
+	}
+	username, found := secret.Data["username"]
+	if !found {
+		return nil, errors.New("\"username\" key not found in secret " + secret.Name)
+	}
+	token, found := secret.Data["token"]
+	if !found {
+		return nil, errors.New("\"token\" key not found in secret " + secret.Name)
+	}
+	m.client, err = newClient(m.gitWebhook.Spec.Gerrit.GerritAPIServerURL, string(username), string(token))
+	if err != nil {
+		log.Error(err, "Unable to parse gerrit url", "url", m.gitWebhook.Spec.Gerrit.GerritAPIServerURL)
+		return nil, err
+	}
+	return m.client, nil
+}
+
+func newClient(serverURL string, username string, token string) (*rest.Client, error) {
+	// gerrit prefixes json responses with a magic string to prevent cross site script inclusion
+	return rest.NewClient(serverURL, rest.BasicAuth(username, token), rest.WithResponsePrefix(")]}'"))
+}
+
+func (m *GerritWebHook) getRemote(ctx context.Context) (*remote, bool, error) {
+	log := log.FromContext(ctx)
+	client, err := m.getClient(ctx)
+	if err != nil {
+		log.Error(err, "unable to create gerrit client")
+		return nil, false, err
+	}
+	remote := &remote{}
+	_, err = client.Do(ctx, http.MethodGet, m.remotePath(), nil, remote)
+	if err != nil {
+		if rest.IsNotFound(err) {
+			return nil, false, nil
+		}
+		log.Error(err, "unable to retrieve remote", "project", m.project(), "remote", m.remoteName())
+		return nil, false, err
+	}
+	return remote, true, nil
+}
+
+func (m *GerritWebHook) isEquivalent(ctx context.Context) (bool, error) {
+	log := log.FromContext(ctx)
+	desiredRemote, err := m.toRemote(ctx)
+	if err != nil {
+		log.Error(err, "unable to convert to gerrit remote")
+		return false, err
+	}
+	actualRemote, found, err := m.getRemote(ctx)
+	if err != nil {
+		log.Error(err, "error while retrieving current remote")
+		return false, err
+	}
+	if !found {
+		return false, nil
+	}
+	sort.Strings(actualRemote.Events)
+	return reflect.DeepEqual(desiredRemote, actualRemote), nil
+}
+
+func (m *GerritWebHook) reconcile(ctx context.Context) error {
+	log := log.FromContext(ctx)
+	equivalent, err := m.isEquivalent(ctx)
+	if err != nil {
+		log.Error(err, "unable to determine if desired state is equal to actual state")
+		return err
+	}
+	if !equivalent {
+		err = m.createOrUpdateRemote(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	return m.recordAppliedWebHook(ctx)
+}
+
+// recordAppliedWebHook records the remote sent to or found in sync on gerrit, gerrit identifies the projects by their name
+func (m *GerritWebHook) recordAppliedWebHook(ctx context.Context) error {
+	log := log.FromContext(ctx)
+	remote, err := m.toRemote(ctx)
+	if err != nil {
+		log.Error(err, "error to convert to gerrit remote")
+		return err
+	}
+	insecureSSL := !remote.SSLVerify
+	m.applied = &redhatcopv1alpha1.AppliedWebHook{
+		RepositoryID: m.project(),
+		Events:       remote.Events,
+		Settings: redhatcopv1alpha1.AppliedWebhookSettings{
+			InsecureSSL: &insecureSSL,
+		},
+	}
+	return nil
+}
+
+// createOrUpdateRemote relies on PUT creating the remote when it does not exist
+func (m *GerritWebHook) createOrUpdateRemote(ctx context.Context) error {
+	log := log.FromContext(ctx)
+	client, err := m.getClient(ctx)
+	if err != nil {
+		log.Error(err, "error get gerrit client")
+		return err
+	}
+	newRemote, err := m.toRemote(ctx)
+	if err != nil {
+		log.Error(err, "error to convert to gerrit remote")
+		return err
+	}
+	_, err = client.Do(ctx, http.MethodPut, m.remotePath(), newRemote, nil)
+	if err != nil {
+		log.Error(err, "unable to create or update remote", "project", m.project(), "remote", m.remoteName())
+		return err
+	}
+	return nil
+}
+
+func (m *GerritWebHook) deleteIfExists(ctx context.Context) error {
+	log := log.FromContext(ctx)
+	client, err := m.getClient(ctx)
+	if err != nil {
+		log.Error(err, "error get gerrit client")
+		return err
+	}
+	_, err = client.Do(ctx, http.MethodDelete, m.remotePath(), nil, nil)
+	if err != nil && !rest.IsNotFound(err) {
+		log.Error(err, "unable to delete remote", "project", m.project(), "remote", m.remoteName())
+		return err
+	}
+	return nil
+}
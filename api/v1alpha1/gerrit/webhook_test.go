@@ -0,0 +1,124 @@
+package gerrit
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1"
+	"github.com/redhat-cop/gitwebhook-operator/api/v1alpha1/webhooktest"
+)
+
+// the project name is escaped as a single path segment
+const testRemotePath = "/a/config/server/webhooks~projects/platform%2Fapp/remotes/default-hook"
+
+// fakeServer serves the remotes of the webhooks plugin, the remotes are addressed by name so they are never listed
+type fakeServer struct {
+	*webhooktest.Server
+	remotes map[string]*remote
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	s := &fakeServer{remotes: map[string]*remote{}}
+	s.Server = webhooktest.NewServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if username, password, ok := r.BasicAuth(); !ok || username != "admin" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		path := r.URL.EscapedPath()
+		switch r.Method {
+		case http.MethodGet:
+			remote, found := s.remotes[path]
+			if !found {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			// gerrit prefixes its json responses
+			w.Write([]byte(")]}'\n"))
+			json.NewEncoder(w).Encode(remote)
+		case http.MethodPut:
+			updated := &remote{}
+			json.NewDecoder(r.Body).Decode(updated)
+			s.remotes[path] = updated
+			w.Write([]byte(")]}'\n"))
+			json.NewEncoder(w).Encode(updated)
+		case http.MethodDelete:
+			if _, found := s.remotes[path]; !found {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			delete(s.remotes, path)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			webhooktest.UnexpectedRequest(t, w, r)
+		}
+	})
+	return s
+}
+
+func newTestWebHook(t *testing.T, server *fakeServer, gitWebhook *redhatcopv1alpha1.GitWebhook) *GerritWebHook {
+	client, err := newClient(server.URL, "admin", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &GerritWebHook{gitWebhook: gitWebhook, client: client}
+}
+
+func TestGerritWebHookLifecycle(t *testing.T) {
+	server := newFakeServer(t)
+	server.remotes["/a/config/server/webhooks~projects/platform%2Fapp/remotes/other"] = &remote{URL: "https://other.example.com/app", Events: []string{"ref-updated"}, SSLVerify: true}
+	gitWebhook := &redhatcopv1alpha1.GitWebhook{}
+	gitWebhook.Name = "hook"
+	gitWebhook.Namespace = "default"
+	gitWebhook.Spec = redhatcopv1alpha1.GitWebhookSpec{
+		Gerrit:          &redhatcopv1alpha1.GerritServerConfig{GerritAPIServerURL: server.URL},
+		RepositoryOwner: "platform",
+		RepositoryName:  "app",
+		WebhookURL:      "https://hooks.example.com/app",
+		Events:          []string{"patchset-created"},
+		Active:          true,
+	}
+
+	webhooktest.RunLifecycle(t, server.Server, func() redhatcopv1alpha1.WebHook { return newTestWebHook(t, server, gitWebhook) },
+		webhooktest.Step{
+			Name:      "create",
+			Mutations: []string{"PUT " + testRemotePath},
+			Check: func(t *testing.T, _ redhatcopv1alpha1.WebHook) {
+				want := &remote{URL: "https://hooks.example.com/app", Events: []string{"patchset-created"}, SSLVerify: true}
+				if got := server.remotes[testRemotePath]; !reflect.DeepEqual(got, want) {
+					t.Fatalf("create: remote = %+v, want %+v", got, want)
+				}
+			},
+		},
+		// the prefixed response is decoded
+		webhooktest.Step{Name: "resync"},
+		webhooktest.Step{
+			Name: "update",
+			Change: func() {
+				gitWebhook.Spec.Events = []string{"patchset-created", "change-merged"}
+				gitWebhook.Spec.InsecureSSL = true
+			},
+			Mutations: []string{"PUT " + testRemotePath},
+			Check: func(t *testing.T, _ redhatcopv1alpha1.WebHook) {
+				want := &remote{URL: "https://hooks.example.com/app", Events: []string{"change-merged", "patchset-created"}, SSLVerify: false}
+				if got := server.remotes[testRemotePath]; !reflect.DeepEqual(got, want) {
+					t.Fatalf("update: remote = %+v, want %+v", got, want)
+				}
+			},
+		},
+		// the other remotes of the project are left alone
+		webhooktest.Step{
+			Name:      "delete",
+			Delete:    true,
+			Mutations: []string{"DELETE " + testRemotePath},
+			Check: func(t *testing.T, _ redhatcopv1alpha1.WebHook) {
+				if _, found := server.remotes[testRemotePath]; found || len(server.remotes) != 1 {
+					t.Fatalf("delete: unexpected remaining remotes %+v", server.remotes)
+				}
+			},
+		},
+		// deleting again is a no-op, the remote not found is ignored
+		webhooktest.Step{Name: "delete again", Delete: true, Mutations: []string{"DELETE " + testRemotePath}},
+	)
+}
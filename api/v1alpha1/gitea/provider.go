@@ -0,0 +1,51 @@
+package gitea
+
+import (
+	redhatcopv1alpha1 "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1"
+)
+
+func init() {
+	redhatcopv1alpha1.RegisterProvider(&provider{})
+}
+
+// provider registers gitea and forgejo with the GitWebhook controller
+type provider struct{}
+
+func (p *provider) Name() string {
+	return "gitea"
+}
+
+func (p *provider) ServerConfig(spec *redhatcopv1alpha1.GitWebhookSpec) redhatcopv1alpha1.ServerConfig {
+	if spec.Gitea == nil {
+		return nil
+	}
+	return spec.Gitea
+}
+
+func (p *provider) NewWebHook(gitWebhook *redhatcopv1alpha1.GitWebhook) redhatcopv1alpha1.WebHook {
+	return FromGitWebhook(gitWebhook)
+}
+
+func (p *provider) CredentialKeys() [][]string {
+	return [][]string{{"token"}}
+}
+
+func (p *provider) Events() []string {
+	return nil
+}
+
+func (p *provider) CanonicalEvents() map[string][]string {
+	return map[string][]string{
+		"push":         {"push"},
+		"tag":          {"create"},
+		"pull_request": {"pull_request"},
+		"issue":        {"issues"},
+		"comment":      {"issue_comment"},
+		"release":      {"release"},
+		"wiki":         {"wiki"},
+	}
+}
+
+func (p *provider) SupportsOwnerWebHooks() bool {
+	return false
+}
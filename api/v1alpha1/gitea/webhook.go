@@ -0,0 +1,345 @@
+package gitea
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1"
+	"github.com/redhat-cop/gitwebhook-operator/api/v1alpha1/rest"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// GiteaWebHook manages repository webhooks on gitea and forgejo, which share the same api
+type GiteaWebHook struct {
+	gitWebhook *redhatcopv1alpha1.GitWebhook
+	client     *rest.Client
+	applied    *redhatcopv1alpha1.AppliedWebHook
+	redhatcopv1alpha1.HookAdoption
+}
+
+// hook is a gitea repository webhook, see https://gitea.com/api/swagger#/repository/repoCreateHook
+type hook struct {
+	ID                  int64             `json:"id,omitempty"`
+	Type                string            `json:"type,omitempty"`
+	Config              map[string]string `json:"config"`
+	Events              []string          `json:"events"`
+	BranchFilter        string            `json:"branch_filter"`
+	AuthorizationHeader string            `json:"authorization_header"`
+	Active              bool              `json:"active"`
+}
+
+// repository is the part of a gitea repository that is read
+type repository struct {
+	ID int64 `json:"id"`
+}
+
+const (
+	pageSize = 50
+	// maxPages bounds the listing of the hooks of a repository, in case the server keeps returning new pages
+	maxPages = 100
+)
+
+var _ redhatcopv1alpha1.WebHook = &GiteaWebHook{}
+var _ redhatcopv1alpha1.DriftDetector = &GiteaWebHook{}
+var _ redhatcopv1alpha1.AppliedWebHookReporter = &GiteaWebHook{}
+var _ redhatcopv1alpha1.HookAdopter = &GiteaWebHook{}
+
+func FromGitWebhook(gitwebhook *redhatcopv1alpha1.GitWebhook) *GiteaWebHook {
+	return &GiteaWebHook{
+		gitWebhook: gitwebhook,
+	}
+}
+
+func (m *GiteaWebHook) Reconcile(ctx context.Context) error {
+	return m.reconcile(ctx)
+}
+
+func (m *GiteaWebHook) Delete(ctx context.Context) error {
+	return m.deleteIfExists(ctx)
+}
+
+func (m *GiteaWebHook) IsEquivalent(ctx context.Context) (bool, error) {
+	return m.isEquivalent(ctx)
+}
+
+func (m *GiteaWebHook) AppliedWebHook() *redhatcopv1alpha1.AppliedWebHook {
+	return m.applied
+}
+
+func (m *GiteaWebHook) repositoryPath() string {
+	return "api/v1/repos/" + url.PathEscape(m.gitWebhook.Spec.RepositoryOwner) + "/" + url.PathEscape(m.gitWebhook.Spec.RepositoryName)
+}
+
+func (m *GiteaWebHook) hooksPath() string {
+	return m.repositoryPath() + "/hooks"
+}
+
+func (m *GiteaWebHook) toWebhook(ctx context.Context) (*hook, error) {
+	log := log.FromContext(ctx)
+	secret, err := m.gitWebhook.GetWebhookSecret(ctx)
+	if err != nil {
+		log.Error(err, "unable to retrieve webhook secret")
+		return nil, err
+	}
+	authorizationHeader, err := m.gitWebhook.GetGiteaAuthorizationHeader(ctx)
+	if err != nil {
+		log.Error(err, "unable to retrieve authorization header")
+		return nil, err
+	}
+	webhookURL, err := m.gitWebhook.GetWebhookURL(ctx)
+	if err != nil {
+		log.Error(err, "unable to retrieve webhook url")
+		return nil, err
+	}
+	events, err := m.gitWebhook.GetNativeEvents()
+	if err != nil {
+		log.Error(err, "unable to translate events")
+		return nil, err
+	}
+	sort.Strings(events)
+	hook := hook{
+		Type: m.gitWebhook.Spec.Gitea.Type,
+		Config: map[string]string{
+			"url":          webhookURL,
+			"content_type": m.gitWebhook.Spec.ContentType,
+			"secret":       secret,
+		},
+		Events:              events,
+		BranchFilter:        m.gitWebhook.Spec.Gitea.BranchFilter,
+		AuthorizationHeader: authorizationHeader,
+		Active:              m.gitWebhook.Spec.Active,
+	}
+	return &hook, nil
+}
+
+func (m *GiteaWebHook) getClient(ctx context.Context) (*rest.Client, error) {
+	if m.client != nil {
+		return m.client, nil
+	}
+	log := log.FromContext(ctx)
+	token, err := m.gitWebhook.GetGitCredential(ctx, m.gitWebhook.Spec.Gitea)
+	if err != nil {
+		log.Error(err, "Unable to retrieve gitea credential", "secret", m.gitWebhook.Spec.Gitea.GitServerCredentials.Name)
+		return nil, err
+	}
+	m.client, err = rest.NewClient(m.gitWebhook.Spec.Gitea.GiteaAPIServerURL, rest.BearerToken(token))
+	if err != nil {
+		log.Error(err, "Unable to parse gitea url", "url", m.gitWebhook.Spec.Gitea.GiteaAPIServerURL)
+		return nil, err
+	}
+	return m.client, nil
+}
+
+func (m *GiteaWebHook) getHook(ctx context.Context) (*hook, bool, error) {
+	log := log.FromContext(ctx)
+	client, err := m.getClient(ctx)
+	if err != nil {
+		log.Error(err, "unable to create gitea client")
+		return nil, false, err
+	}
+	webhookURL, err := m.gitWebhook.GetWebhookURL(ctx)
+	if err != nil {
+		log.Error(err, "unable to retrieve webhook url")
+		return nil, false, err
+	}
+	finder := redhatcopv1alpha1.NewHookFinder[*hook](m.gitWebhook, &m.HookAdoption, webhookURL)
+	seen := map[int64]bool{}
+	fullPageSize := pageSize
+	for page := 1; ; page++ {
+		if page > maxPages {
+			return nil, false, errors.New("more than " + strconv.Itoa(maxPages) + " pages of hooks for repo " + m.gitWebhook.Spec.RepositoryOwner + "/" + m.gitWebhook.Spec.RepositoryName)
+		}
+		hooks := []*hook{}
+		resp, err := client.Do(ctx, http.MethodGet, m.hooksPath()+"?limit="+strconv.Itoa(pageSize)+"&page="+strconv.Itoa(page), nil, &hooks)
+		if err != nil {
+			log.Error(err, "unable to list hooks", "for repo", m.gitWebhook.Spec.RepositoryOwner+"/"+m.gitWebhook.Spec.RepositoryName)
+			return nil, false, err
+		}
+		repeated := false
+		for _, hook := range hooks {
+			// gogs and older gitea versions ignore the page parameter and return the same hooks again
+			if seen[hook.ID] {
+				repeated = true
+				break
+			}
+			seen[hook.ID] = true
+			if finder.Owned(hook, strconv.FormatInt(hook.ID, 10), hook.Config["url"]) {
+				return hook, true, nil
+			}
+		}
+		// gitea caps the page size to its MAX_RESPONSE_ITEMS setting, so the first page tells the size of the full pages
+		if page == 1 && len(hooks) > 0 && len(hooks) < fullPageSize {
+			fullPageSize = len(hooks)
+		}
+		if total := totalCount(resp); repeated || len(hooks) < fullPageSize || (total >= 0 && len(seen) >= total) {
+			break
+		}
+	}
+	return finder.Claim()
+}
+
+// totalCount returns the number of hooks announced by the X-Total-Count header, or -1 when the server does not send it
+func totalCount(resp *http.Response) int {
+	total, err := strconv.Atoi(resp.Header.Get("X-Total-Count"))
+	if err != nil {
+		return -1
+	}
+	return total
+}
+
+func (m *GiteaWebHook) isEquivalent(ctx context.Context) (bool, error) {
+	log := log.FromContext(ctx)
+	desiredHook, err := m.toWebhook(ctx)
+	if err != nil {
+		log.Error(err, "unable to convert to gitea webhook")
+		return false, err
+	}
+	actualHook, found, err := m.getHook(ctx)
+	if err != nil {
+		log.Error(err, "error while retrieving current webhook")
+		return false, err
+	}
+	if !found {
+		return false, nil
+	}
+	secret, err := m.gitWebhook.GetWebhookSecret(ctx)
+	if err != nil {
+		log.Error(err, "unable to retrieve webhook secret")
+		return false, err
+	}
+	// the secret is not returned by the git server, a changed secret is detected with the fingerprint of the applied secret
+	if !m.gitWebhook.IsWebhookSecretApplied(secret) {
+		return false, nil
+	}
+	actualHook.ID = 0
+	sort.Strings(actualHook.Events)
+	// gitea does not return the secret nor the http method
+	delete(actualHook.Config, "secret")
+	delete(actualHook.Config, "http_method")
+	delete(desiredHook.Config, "secret")
+	return reflect.DeepEqual(desiredHook, actualHook), nil
+}
+
+func (m *GiteaWebHook) reconcile(ctx context.Context) error {
+	log := log.FromContext(ctx)
+	equivalent, err := m.isEquivalent(ctx)
+	if err != nil {
+		log.Error(err, "unable to determine if desired state is equal to actual state")
+		return err
+	}
+	if !equivalent {
+		err = m.createOrUpdateWebhook(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	return m.recordAppliedWebHook(ctx)
+}
+
+// recordAppliedWebHook records the webhook sent to or found in sync on gitea
+func (m *GiteaWebHook) recordAppliedWebHook(ctx context.Context) error {
+	log := log.FromContext(ctx)
+	hook, err := m.toWebhook(ctx)
+	if err != nil {
+		log.Error(err, "error to convert to gitea hook")
+		return err
+	}
+	repositoryID, err := m.getRepositoryID(ctx)
+	if err != nil {
+		log.Error(err, "unable to retrieve repository id", "repository", m.gitWebhook.Spec.RepositoryOwner+"/"+m.gitWebhook.Spec.RepositoryName)
+		return err
+	}
+	m.applied = &redhatcopv1alpha1.AppliedWebHook{
+		RepositoryID: repositoryID,
+		Events:       hook.Events,
+		Settings: redhatcopv1alpha1.AppliedWebhookSettings{
+			ContentType:           hook.Config["content_type"],
+			Active:                &hook.Active,
+			PushEventBranchFilter: hook.BranchFilter,
+		},
+	}
+	return nil
+}
+
+// getRepositoryID returns the id of the repository, the repository of a GitWebhook cannot be changed so the id recorded in the status is reused
+func (m *GiteaWebHook) getRepositoryID(ctx context.Context) (string, error) {
+	if m.gitWebhook.Status.RepositoryID != "" {
+		return m.gitWebhook.Status.RepositoryID, nil
+	}
+	client, err := m.getClient(ctx)
+	if err != nil {
+		return "", err
+	}
+	repository := &repository{}
+	_, err = client.Do(ctx, http.MethodGet, m.repositoryPath(), nil, repository)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(repository.ID, 10), nil
+}
+
+func (m *GiteaWebHook) createOrUpdateWebhook(ctx context.Context) error {
+	log := log.FromContext(ctx)
+	actualHook, found, err := m.getHook(ctx)
+	if err != nil {
+		log.Error(err, "error while retrieving webhook")
+		return err
+	}
+	client, err := m.getClient(ctx)
+	if err != nil {
+		log.Error(err, "error get gitea client")
+		return err
+	}
+	newHook, err := m.toWebhook(ctx)
+	if err != nil {
+		log.Error(err, "error to convert to gitea hook")
+		return err
+	}
+	if !found {
+		//we need to create
+		createdHook := &hook{}
+		_, err = client.Do(ctx, http.MethodPost, m.hooksPath(), newHook, createdHook)
+		if err != nil {
+			log.Error(err, "unable to create new hook")
+			return err
+		}
+		m.gitWebhook.SetOwnedHook(strconv.FormatInt(createdHook.ID, 10))
+	} else {
+		//we need to update, the type of a hook cannot be changed
+		newHook.Type = ""
+		_, err = client.Do(ctx, http.MethodPatch, m.hooksPath()+"/"+strconv.FormatInt(actualHook.ID, 10), newHook, nil)
+		if err != nil {
+			log.Error(err, "unable to update gitea webhook")
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *GiteaWebHook) deleteIfExists(ctx context.Context) error {
+	log := log.FromContext(ctx)
+	hook, found, err := m.getHook(ctx)
+	if err != nil {
+		log.Error(err, "error while retrieving webhook")
+		return err
+	}
+	if !found {
+		return nil
+	}
+	client, err := m.getClient(ctx)
+	if err != nil {
+		log.Error(err, "error get gitea client")
+		return err
+	}
+	_, err = client.Do(ctx, http.MethodDelete, m.hooksPath()+"/"+strconv.FormatInt(hook.ID, 10), nil, nil)
+	if err != nil && !rest.IsNotFound(err) {
+		log.Error(err, "unable to delete webhook")
+		return err
+	}
+	return nil
+}
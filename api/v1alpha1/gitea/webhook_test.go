@@ -0,0 +1,189 @@
+package gitea
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1"
+	"github.com/redhat-cop/gitwebhook-operator/api/v1alpha1/rest"
+	"github.com/redhat-cop/gitwebhook-operator/api/v1alpha1/webhooktest"
+)
+
+const testHooksPath = "/api/v1/repos/team/app/hooks"
+
+// fakeServer serves the hooks of a repository, it caps the page size to one hook like a gitea server with MAX_RESPONSE_ITEMS set to 1
+type fakeServer struct {
+	*webhooktest.Server
+	hooks  []*hook
+	lastID int64
+	// ignorePage returns all the hooks whatever the page, like gogs
+	ignorePage bool
+	// endless returns a new hook for every page
+	endless bool
+}
+
+func newFakeServer(t *testing.T, hooks ...*hook) *fakeServer {
+	s := &fakeServer{hooks: hooks, lastID: 100}
+	s.Server = webhooktest.NewServer(t, func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, testHooksPath+"/")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == strings.TrimSuffix(testHooksPath, "/hooks"):
+			json.NewEncoder(w).Encode(&repository{ID: 42})
+		case r.Method == http.MethodGet && r.URL.Path == testHooksPath:
+			page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+			hooks := []*hook{}
+			switch {
+			case s.ignorePage:
+				hooks = s.hooks
+			case s.endless:
+				hooks = append(hooks, &hook{ID: int64(page), Config: map[string]string{"url": "https://other.example.com/" + strconv.Itoa(page)}})
+			case page >= 1 && page <= len(s.hooks):
+				hooks = append(hooks, s.hooks[page-1])
+			}
+			json.NewEncoder(w).Encode(hooks)
+		case r.Method == http.MethodPost && r.URL.Path == testHooksPath:
+			created := &hook{}
+			json.NewDecoder(r.Body).Decode(created)
+			s.lastID++
+			created.ID = s.lastID
+			// gitea does not return the secret
+			delete(created.Config, "secret")
+			s.hooks = append(s.hooks, created)
+			json.NewEncoder(w).Encode(created)
+		case r.Method == http.MethodPatch && id != r.URL.Path:
+			for i, existing := range s.hooks {
+				if strconv.FormatInt(existing.ID, 10) == id {
+					updated := &hook{}
+					json.NewDecoder(r.Body).Decode(updated)
+					updated.ID = existing.ID
+					updated.Type = existing.Type
+					delete(updated.Config, "secret")
+					s.hooks[i] = updated
+					json.NewEncoder(w).Encode(updated)
+					return
+				}
+			}
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodDelete && id != r.URL.Path:
+			for i, existing := range s.hooks {
+				if strconv.FormatInt(existing.ID, 10) == id {
+					s.hooks = append(s.hooks[:i], s.hooks[i+1:]...)
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+			}
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			webhooktest.UnexpectedRequest(t, w, r)
+		}
+	})
+	return s
+}
+
+func newTestWebHook(t *testing.T, server *fakeServer, gitWebhook *redhatcopv1alpha1.GitWebhook) *GiteaWebHook {
+	client, err := rest.NewClient(server.URL, rest.BearerToken("token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &GiteaWebHook{gitWebhook: gitWebhook, client: client}
+}
+
+func TestGiteaWebHookLifecycle(t *testing.T) {
+	server := newFakeServer(t,
+		&hook{ID: 1, Type: "gitea", Config: map[string]string{"url": "https://other.example.com/1", "content_type": "json"}, Events: []string{"push"}},
+		&hook{ID: 2, Type: "gitea", Config: map[string]string{"url": "https://other.example.com/2", "content_type": "json"}, Events: []string{"push"}},
+	)
+	gitWebhook := newTestGitWebhook(server.URL)
+
+	webhooktest.RunLifecycle(t, server.Server, func() redhatcopv1alpha1.WebHook { return newTestWebHook(t, server, gitWebhook) },
+		// the hooks of the other receivers are listed over several short pages
+		webhooktest.Step{
+			Name:      "create",
+			Mutations: []string{"POST " + testHooksPath},
+			Check: func(t *testing.T, _ redhatcopv1alpha1.WebHook) {
+				if len(server.hooks) != 3 || server.hooks[2].Config["url"] != "https://hooks.example.com/app" || server.hooks[2].Type != "gitea" {
+					t.Fatalf("create: hook not created as expected: %+v", server.hooks)
+				}
+				if gitWebhook.Status.HookID != "101" {
+					t.Fatalf("create: hookID = %q, want 101", gitWebhook.Status.HookID)
+				}
+			},
+		},
+		// the owned hook is found on the last page
+		webhooktest.Step{Name: "resync"},
+		webhooktest.Step{
+			Name: "update",
+			Change: func() {
+				gitWebhook.Spec.Events = []string{"push", "pull_request"}
+				gitWebhook.Spec.Gitea.BranchFilter = "main"
+			},
+			Mutations: []string{"PATCH " + testHooksPath + "/101"},
+			Check: func(t *testing.T, webHook redhatcopv1alpha1.WebHook) {
+				if len(server.hooks) != 3 || len(server.hooks[2].Events) != 2 || server.hooks[2].BranchFilter != "main" || server.hooks[2].ID != 101 {
+					t.Fatalf("update: hook not updated as expected: %+v", server.hooks[2])
+				}
+				// the branch filter of gitea is reported as the push event branch filter
+				if applied := webHook.(*GiteaWebHook).AppliedWebHook(); applied == nil || applied.RepositoryID != "42" || !reflect.DeepEqual(applied.Events, []string{"pull_request", "push"}) ||
+					applied.Settings.PushEventBranchFilter != "main" || applied.Settings.ContentType != "json" || applied.Settings.InsecureSSL != nil {
+					t.Fatalf("update: applied webhook = %+v", applied)
+				}
+			},
+		},
+		// the hooks of the other receivers are left alone
+		webhooktest.Step{
+			Name:      "delete",
+			Delete:    true,
+			Mutations: []string{"DELETE " + testHooksPath + "/101"},
+			Check: func(t *testing.T, _ redhatcopv1alpha1.WebHook) {
+				if len(server.hooks) != 2 || server.hooks[0].ID != 1 || server.hooks[1].ID != 2 {
+					t.Fatalf("delete: unexpected remaining hooks %+v", server.hooks)
+				}
+			},
+		},
+	)
+}
+
+func newTestGitWebhook(serverURL string) *redhatcopv1alpha1.GitWebhook {
+	gitWebhook := &redhatcopv1alpha1.GitWebhook{}
+	gitWebhook.Name = "hook"
+	gitWebhook.Namespace = "default"
+	gitWebhook.Spec = redhatcopv1alpha1.GitWebhookSpec{
+		Gitea:           &redhatcopv1alpha1.GiteaServerConfig{GiteaAPIServerURL: serverURL, Type: "gitea"},
+		RepositoryOwner: "team",
+		RepositoryName:  "app",
+		WebhookURL:      "https://hooks.example.com/app",
+		Events:          []string{"push"},
+		ContentType:     "json",
+		Active:          true,
+	}
+	return gitWebhook
+}
+
+func TestGiteaWebHookServerIgnoringPage(t *testing.T) {
+	server := newFakeServer(t,
+		&hook{ID: 1, Type: "gitea", Config: map[string]string{"url": "https://other.example.com/1", "content_type": "json"}, Events: []string{"push"}},
+		&hook{ID: 2, Type: "gitea", Config: map[string]string{"url": "https://other.example.com/2", "content_type": "json"}, Events: []string{"push"}},
+	)
+	server.ignorePage = true
+	gitWebhook := newTestGitWebhook(server.URL)
+
+	webhooktest.RunLifecycle(t, server.Server, func() redhatcopv1alpha1.WebHook { return newTestWebHook(t, server, gitWebhook) },
+		webhooktest.Step{Name: "create", Mutations: []string{"POST " + testHooksPath}},
+		webhooktest.Step{Name: "resync"},
+	)
+}
+
+func TestGiteaWebHookEndlessPages(t *testing.T) {
+	server := newFakeServer(t)
+	server.endless = true
+	gitWebhook := newTestGitWebhook(server.URL)
+
+	// the hooks do not fit in the maximum number of pages
+	webhooktest.RunLifecycle(t, server.Server, func() redhatcopv1alpha1.WebHook { return newTestWebHook(t, server, gitWebhook) },
+		webhooktest.Step{Name: "create", Error: true},
+	)
+}
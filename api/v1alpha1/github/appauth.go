@@ -0,0 +1,208 @@
+package github
+
+import (
+	"container/list"
+	"context"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/go-github/v48/github"
+	"golang.org/x/oauth2"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// installation tokens are valid for one hour, they are renewed a few minutes before they expire
+const tokenRefreshMargin = 5 * time.Minute
+
+// maxAppInstallations bounds the number of cached app installations, the key of an entry changes with the private key or the repository, so the stale entries are evicted rather than kept forever
+const maxAppInstallations = 256
+
+// appInstallations caches the app installations and their tokens, so that installation tokens are reused across reconcile cycles
+var appInstallations = newInstallationCache(maxAppInstallations)
+
+// installationCache is a least recently used cache of app installations
+type installationCache struct {
+	lock     sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List
+}
+
+type installationEntry struct {
+	key          string
+	installation *appInstallation
+}
+
+func newInstallationCache(capacity int) *installationCache {
+	return &installationCache{
+		capacity: capacity,
+		entries:  map[string]*list.Element{},
+		order:    list.New(),
+	}
+}
+
+func (c *installationCache) get(key string) (*appInstallation, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	element, found := c.entries[key]
+	if !found {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*installationEntry).installation, true
+}
+
+// add caches the installation and returns it, or returns the installation already cached with the same key, which may hold a valid token
+func (c *installationCache) add(key string, installation *appInstallation) *appInstallation {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if element, found := c.entries[key]; found {
+		c.order.MoveToFront(element)
+		return element.Value.(*installationEntry).installation
+	}
+	c.entries[key] = c.order.PushFront(&installationEntry{key: key, installation: installation})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*installationEntry).key)
+	}
+	return installation
+}
+
+// appInstallation is an installation of a github app, it holds the last installation token
+type appInstallation struct {
+	baseURL        string
+	appID          int64
+	privateKey     *rsa.PrivateKey
+	installationID int64
+	lock           sync.Mutex
+	token          *oauth2.Token
+}
+
+// tokenSource returns a token source that exchanges a jwt signed with the private key of the app for an installation token when the last one expires,
+// the exchange is bound to the context of the caller
+func (i *appInstallation) tokenSource(ctx context.Context) oauth2.TokenSource {
+	return &installationTokenSource{ctx: ctx, installation: i}
+}
+
+type installationTokenSource struct {
+	ctx          context.Context
+	installation *appInstallation
+}
+
+func (s *installationTokenSource) Token() (*oauth2.Token, error) {
+	i := s.installation
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	if i.token.Valid() {
+		return i.token, nil
+	}
+	git, err := newAppClient(s.ctx, i.baseURL, i.appID, i.privateKey)
+	if err != nil {
+		return nil, err
+	}
+	token, _, err := git.Apps.CreateInstallationToken(s.ctx, i.installationID, nil)
+	if err != nil {
+		return nil, err
+	}
+	i.token = &oauth2.Token{
+		AccessToken: token.GetToken(),
+		Expiry:      token.GetExpiresAt().Add(-tokenRefreshMargin),
+	}
+	return i.token, nil
+}
+
+// appTokenSource returns a token source for the github app described by the "appID", "privateKey" and optionally "installationID" keys of the secret.
+// When the installation id is not provided, the installation of the app on the repository, or on the organization for organization webhooks, is looked up.
+func appTokenSource(ctx context.Context, secret *corev1.Secret, baseURL string, owner string, repo string) (oauth2.TokenSource, error) {
+	appIDData, found := secret.Data["appID"]
+	if !found {
+		return nil, errors.New("neither \"token\" nor \"appID\" key found in secret " + secret.Name)
+	}
+	appID, err := strconv.ParseInt(strings.TrimSpace(string(appIDData)), 10, 64)
+	if err != nil {
+		return nil, errors.New("invalid \"appID\" in secret " + secret.Name + ": " + err.Error())
+	}
+	privateKeyData, found := secret.Data["privateKey"]
+	if !found {
+		return nil, errors.New("\"privateKey\" key not found in secret " + secret.Name)
+	}
+	installationIDData, hasInstallationID := secret.Data["installationID"]
+
+	privateKeyHash := sha256.Sum256(privateKeyData)
+	cacheKey := baseURL + "|" + strconv.FormatInt(appID, 10) + "|" + hex.EncodeToString(privateKeyHash[:]) + "|"
+	if hasInstallationID {
+		cacheKey += string(installationIDData)
+	} else {
+		cacheKey += owner + "/" + repo
+	}
+	if installation, found := appInstallations.get(cacheKey); found {
+		return installation.tokenSource(ctx), nil
+	}
+
+	// the installation is looked up without holding the cache lock, so that a slow git server does not block the other GitWebhooks
+	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(privateKeyData)
+	if err != nil {
+		return nil, errors.New("invalid \"privateKey\" in secret " + secret.Name + ": " + err.Error())
+	}
+	var installationID int64
+	if hasInstallationID {
+		installationID, err = strconv.ParseInt(strings.TrimSpace(string(installationIDData)), 10, 64)
+		if err != nil {
+			return nil, errors.New("invalid \"installationID\" in secret " + secret.Name + ": " + err.Error())
+		}
+	} else {
+		installationID, err = findInstallationID(ctx, baseURL, appID, privateKey, owner, repo)
+		if err != nil {
+			return nil, err
+		}
+	}
+	installation := appInstallations.add(cacheKey, &appInstallation{
+		baseURL:        baseURL,
+		appID:          appID,
+		privateKey:     privateKey,
+		installationID: installationID,
+	})
+	return installation.tokenSource(ctx), nil
+}
+
+func findInstallationID(ctx context.Context, baseURL string, appID int64, privateKey *rsa.PrivateKey, owner string, repo string) (int64, error) {
+	git, err := newAppClient(ctx, baseURL, appID, privateKey)
+	if err != nil {
+		return 0, err
+	}
+	if repo == "" {
+		installation, _, err := git.Apps.FindOrganizationInstallation(ctx, owner)
+		if err != nil {
+			return 0, err
+		}
+		return installation.GetID(), nil
+	}
+	installation, _, err := git.Apps.FindRepositoryInstallation(ctx, owner, repo)
+	if err != nil {
+		return 0, err
+	}
+	return installation.GetID(), nil
+}
+
+// newAppClient creates a client authenticated as the app, which can only be used to manage the app installations
+func newAppClient(ctx context.Context, baseURL string, appID int64, privateKey *rsa.PrivateKey) (*github.Client, error) {
+	now := time.Now()
+	// the issue time is set in the past to allow for clock drift, github rejects jwts valid for more than 10 minutes
+	token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.RegisteredClaims{
+		IssuedAt:  jwt.NewNumericDate(now.Add(-time.Minute)),
+		ExpiresAt: jwt.NewNumericDate(now.Add(9 * time.Minute)),
+		Issuer:    strconv.FormatInt(appID, 10),
+	}).SignedString(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	return newClient(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token}), baseURL)
+}
@@ -0,0 +1,203 @@
+package github
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	redhatcopv1alpha1 "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestInstallationCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newInstallationCache(2)
+	first := &appInstallation{installationID: 1}
+	cache.add("first", first)
+	cache.add("second", &appInstallation{installationID: 2})
+	// reading the first entry makes the second one the least recently used
+	if _, found := cache.get("first"); !found {
+		t.Fatal("first entry not found")
+	}
+	cache.add("third", &appInstallation{installationID: 3})
+
+	tests := []struct {
+		key   string
+		found bool
+	}{
+		{"first", true},
+		{"second", false},
+		{"third", true},
+	}
+	for _, test := range tests {
+		if _, found := cache.get(test.key); found != test.found {
+			t.Errorf("get(%q) found = %v, want %v", test.key, found, test.found)
+		}
+	}
+	if len(cache.entries) != 2 || cache.order.Len() != 2 {
+		t.Errorf("cache holds %d entries and %d list elements, want 2", len(cache.entries), cache.order.Len())
+	}
+	// adding a cached key keeps the cached installation, which may hold a valid token
+	if got := cache.add("first", &appInstallation{installationID: 4}); got != first {
+		t.Errorf("add() of a cached key = %+v, want the cached installation", got)
+	}
+}
+
+// fakeAppServer serves the installation endpoints of a github app, it checks the jwt signed by the app
+type fakeAppServer struct {
+	*httptest.Server
+	lock       sync.Mutex
+	tokens     int
+	hookOwners []string
+}
+
+func newFakeAppServer(t *testing.T, privateKey *rsa.PrivateKey) *fakeAppServer {
+	s := &fakeAppServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		authorization := r.Header.Get("Authorization")
+		if r.URL.Path == "/repos/team/app/hooks" || r.URL.Path == "/orgs/team/hooks" {
+			s.hookOwners = append(s.hookOwners, authorization)
+			json.NewEncoder(w).Encode([]interface{}{})
+			return
+		}
+		claims := &jwt.RegisteredClaims{}
+		_, err := jwt.ParseWithClaims(strings.TrimPrefix(authorization, "Bearer "), claims, func(token *jwt.Token) (interface{}, error) {
+			return &privateKey.PublicKey, nil
+		})
+		if err != nil || claims.Issuer != "12" {
+			t.Errorf("%s %s: invalid app jwt %q: %v", r.Method, r.URL.Path, authorization, err)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/team/app/installation":
+			json.NewEncoder(w).Encode(map[string]interface{}{"id": 34})
+		case r.Method == http.MethodGet && r.URL.Path == "/orgs/team/installation":
+			json.NewEncoder(w).Encode(map[string]interface{}{"id": 56})
+		case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/app/installations/") && strings.HasSuffix(r.URL.Path, "/access_tokens"):
+			s.tokens++
+			installationID := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/app/installations/"), "/access_tokens")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"token":      "installation-" + installationID,
+				"expires_at": time.Now().Add(time.Hour).Format(time.RFC3339),
+			})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return s
+}
+
+func TestAppTokenSource(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateKeyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})
+
+	tests := []struct {
+		name           string
+		installationID string
+		repo           string
+		wantToken      string
+	}{
+		{
+			name:      "repository installation",
+			repo:      "app",
+			wantToken: "installation-34",
+		},
+		{
+			name:      "organization installation",
+			repo:      "",
+			wantToken: "installation-56",
+		},
+		{
+			name:           "explicit installation",
+			installationID: "78",
+			repo:           "app",
+			wantToken:      "installation-78",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := newFakeAppServer(t, privateKey)
+			defer server.Close()
+			secret := &corev1.Secret{Data: map[string][]byte{
+				"appID":      []byte("12"),
+				"privateKey": privateKeyPEM,
+			}}
+			if test.installationID != "" {
+				secret.Data["installationID"] = []byte(test.installationID)
+			}
+			ctx := context.TODO()
+
+			// the installation token is exchanged once and reused by the following reconciles
+			for i := 0; i < 2; i++ {
+				ts, err := appTokenSource(ctx, secret, server.URL, "team", test.repo)
+				if err != nil {
+					t.Fatalf("appTokenSource() error = %v", err)
+				}
+				git, err := newClient(ctx, ts, server.URL)
+				if err != nil {
+					t.Fatal(err)
+				}
+				gitWebhook := &redhatcopv1alpha1.GitWebhook{}
+				gitWebhook.Spec.OwnerType = "organization"
+				gitWebhook.Spec.RepositoryOwner = "team"
+				gitWebhook.Spec.RepositoryName = test.repo
+				if _, _, err := FromGitWebhook(gitWebhook).listHooks(ctx, git, nil); err != nil {
+					t.Fatalf("listHooks() error = %v", err)
+				}
+			}
+			if server.tokens != 1 {
+				t.Errorf("%d installation tokens created, want 1", server.tokens)
+			}
+			for _, authorization := range server.hookOwners {
+				if authorization != "Bearer "+test.wantToken {
+					t.Errorf("hooks listed with Authorization %q, want the installation token %q", authorization, test.wantToken)
+				}
+			}
+		})
+	}
+}
+
+func TestAppTokenSourceHonorsContext(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateKeyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})
+	blocked := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-blocked
+	}))
+	defer server.Close()
+	defer close(blocked)
+	secret := &corev1.Secret{Data: map[string][]byte{
+		"appID":          []byte("12"),
+		"privateKey":     privateKeyPEM,
+		"installationID": []byte("90"),
+	}}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 100*time.Millisecond)
+	defer cancel()
+	ts, err := appTokenSource(ctx, secret, server.URL, "team", "app")
+	if err != nil {
+		t.Fatalf("appTokenSource() error = %v", err)
+	}
+	if _, err := ts.Token(); err == nil {
+		t.Fatal("Token() succeeded against a hung server")
+	}
+}
@@ -0,0 +1,239 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/google/go-github/v48/github"
+	redhatcopv1alpha1 "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1"
+	"golang.org/x/oauth2"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// GlobalHook manages a github enterprise server global webhook, see https://docs.github.com/en/enterprise-server@latest/rest/enterprise-admin/global-webhooks
+// go-github does not wrap the admin hooks api, so requests are built with the generic client methods.
+type GlobalHook struct {
+	gitHubGlobalHook *redhatcopv1alpha1.GitHubGlobalHook
+	git              *github.Client
+	redhatcopv1alpha1.HookAdoption
+}
+
+const globalHooksPath = "admin/hooks"
+
+var _ redhatcopv1alpha1.WebHook = &GlobalHook{}
+var _ redhatcopv1alpha1.HookAdopter = &GlobalHook{}
+
+func FromGitHubGlobalHook(gitHubGlobalHook *redhatcopv1alpha1.GitHubGlobalHook) *GlobalHook {
+	return &GlobalHook{
+		gitHubGlobalHook: gitHubGlobalHook,
+	}
+}
+
+func (m *GlobalHook) Reconcile(ctx context.Context) error {
+	log := log.FromContext(ctx)
+	equivalent, err := m.isEquivalent(ctx)
+	if err != nil {
+		log.Error(err, "unable to determine if desired state is equal to actual state")
+		return err
+	}
+	if equivalent {
+		return nil
+	}
+	return m.createOrUpdateWebhook(ctx)
+}
+
+func (m *GlobalHook) Delete(ctx context.Context) error {
+	return m.deleteIfExists(ctx)
+}
+
+func (m *GlobalHook) toWebhook(ctx context.Context) (*github.Hook, error) {
+	log := log.FromContext(ctx)
+	secret, err := m.gitHubGlobalHook.GetWebhookSecret(ctx)
+	if err != nil {
+		log.Error(err, "unable to retrieve webhook secret")
+		return nil, err
+	}
+	var insecure string = "0"
+	if m.gitHubGlobalHook.Spec.InsecureSSL {
+		insecure = "1"
+	}
+	events := make([]string, len(m.gitHubGlobalHook.Spec.Events))
+	copy(events, m.gitHubGlobalHook.Spec.Events)
+	sort.Strings(events)
+	hook := github.Hook{
+		Events: events,
+		Active: &m.gitHubGlobalHook.Spec.Active,
+		Name:   &web,
+		Config: map[string]interface{}{
+			"content_type": m.gitHubGlobalHook.Spec.ContentType,
+			"insecure_ssl": insecure,
+			"url":          m.gitHubGlobalHook.Spec.WebhookURL,
+			"secret":       secret,
+		},
+	}
+	return &hook, nil
+}
+
+func (m *GlobalHook) getClient(ctx context.Context) (*github.Client, error) {
+	if m.git != nil {
+		return m.git, nil
+	}
+	log := log.FromContext(ctx)
+	token, err := m.gitHubGlobalHook.GetGitCredential(ctx)
+	if err != nil {
+		log.Error(err, "Unable to retrieve github credential", "secret", m.gitHubGlobalHook.Spec.GitHub.GitServerCredentials.Namespace+"/"+m.gitHubGlobalHook.Spec.GitHub.GitServerCredentials.Name)
+		return nil, err
+	}
+	ts := oauth2.StaticTokenSource(
+		&oauth2.Token{AccessToken: token},
+	)
+	git, err := newClient(ctx, ts, m.gitHubGlobalHook.Spec.GitHub.GitHubAPIServerURL)
+	if err != nil {
+		log.Error(err, "Unable to parse github url", "url", m.gitHubGlobalHook.Spec.GitHub.GitHubAPIServerURL)
+		return nil, err
+	}
+	m.git = git
+	return git, nil
+}
+
+// getHook returns the global hook created or adopted by the GitHubGlobalHook, or else the first global hook with its url when the adoption policy allows to adopt it
+func (m *GlobalHook) getHook(ctx context.Context) (*github.Hook, bool, error) {
+	log := log.FromContext(ctx)
+	git, err := m.getClient(ctx)
+	if err != nil {
+		log.Error(err, "unable to create github client")
+		return nil, false, err
+	}
+	finder := redhatcopv1alpha1.NewHookFinder[*github.Hook](m.gitHubGlobalHook, &m.HookAdoption, m.gitHubGlobalHook.Spec.WebhookURL)
+	page := 1
+	for {
+		req, err := git.NewRequest(http.MethodGet, globalHooksPath+"?per_page=100&page="+strconv.Itoa(page), nil)
+		if err != nil {
+			log.Error(err, "unable to create request")
+			return nil, false, err
+		}
+		hooks := []*github.Hook{}
+		response, err := git.Do(ctx, req, &hooks)
+		if err != nil {
+			log.Error(err, "unable to list global hooks")
+			return nil, false, err
+		}
+		for _, hook := range hooks {
+			if hookURL, _ := hook.Config["url"].(string); finder.Owned(hook, strconv.FormatInt(hook.GetID(), 10), hookURL) {
+				return hook, true, nil
+			}
+		}
+		if response.NextPage == 0 {
+			break
+		}
+		page = response.NextPage
+	}
+	return finder.Claim()
+}
+
+func (m *GlobalHook) isEquivalent(ctx context.Context) (bool, error) {
+	log := log.FromContext(ctx)
+	desiredHook, err := m.toWebhook(ctx)
+	if err != nil {
+		log.Error(err, "unable to convert to github global hook")
+		return false, err
+	}
+	actualHook, found, err := m.getHook(ctx)
+	if err != nil {
+		log.Error(err, "error while retrieving current global hook")
+		return false, err
+	}
+	if !found {
+		return false, nil
+	}
+
+	actualHook.CreatedAt = nil
+	actualHook.UpdatedAt = nil
+	actualHook.ID = nil
+	actualHook.URL = nil
+	actualHook.Type = nil
+	actualHook.LastResponse = nil
+	actualHook.PingURL = nil
+	actualHook.TestURL = nil
+	sort.Strings(actualHook.Events)
+
+	// github does not return the secret, it is compared with the fingerprint of the secret last applied
+	secret, _ := desiredHook.Config["secret"].(string)
+	if !m.gitHubGlobalHook.IsWebhookSecretApplied(secret) {
+		return false, nil
+	}
+	delete(actualHook.Config, "secret")
+	delete(desiredHook.Config, "secret")
+
+	return reflect.DeepEqual(desiredHook, actualHook), nil
+}
+
+func (m *GlobalHook) createOrUpdateWebhook(ctx context.Context) error {
+	log := log.FromContext(ctx)
+	actualHook, found, err := m.getHook(ctx)
+	if err != nil {
+		log.Error(err, "error while retrieving global hook")
+		return err
+	}
+	git, err := m.getClient(ctx)
+	if err != nil {
+		log.Error(err, "error get github client")
+		return err
+	}
+	newHook, err := m.toWebhook(ctx)
+	if err != nil {
+		log.Error(err, "error to convert to github global hook")
+		return err
+	}
+	method, path := http.MethodPost, globalHooksPath
+	if found {
+		//we need to update
+		method, path = http.MethodPatch, globalHooksPath+"/"+strconv.FormatInt(*actualHook.ID, 10)
+	}
+	req, err := git.NewRequest(method, path, newHook)
+	if err != nil {
+		log.Error(err, "unable to create request")
+		return err
+	}
+	hook := &github.Hook{}
+	_, err = git.Do(ctx, req, hook)
+	if err != nil {
+		log.Error(err, "unable to create or update global hook")
+		return err
+	}
+	secret, _ := newHook.Config["secret"].(string)
+	m.gitHubGlobalHook.SetOwnedHook(strconv.FormatInt(hook.GetID(), 10))
+	m.gitHubGlobalHook.SetAppliedWebhookSecret(secret)
+	return nil
+}
+
+func (m *GlobalHook) deleteIfExists(ctx context.Context) error {
+	log := log.FromContext(ctx)
+	hook, found, err := m.getHook(ctx)
+	if err != nil {
+		log.Error(err, "error while retrieving global hook")
+		return err
+	}
+	if !found {
+		return nil
+	}
+	git, err := m.getClient(ctx)
+	if err != nil {
+		log.Error(err, "error get github client")
+		return err
+	}
+	req, err := git.NewRequest(http.MethodDelete, globalHooksPath+"/"+strconv.FormatInt(*hook.ID, 10), nil)
+	if err != nil {
+		log.Error(err, "unable to create request")
+		return err
+	}
+	_, err = git.Do(ctx, req, nil)
+	if err != nil {
+		log.Error(err, "unable to delete global hook")
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1"
+	"github.com/redhat-cop/gitwebhook-operator/api/v1alpha1/webhooktest"
+	"golang.org/x/oauth2"
+)
+
+// newFakeGlobalHooksServer serves the given global hooks
+func newFakeGlobalHooksServer(t *testing.T, hooks []map[string]interface{}) *webhooktest.Server {
+	return webhooktest.NewServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v3/admin/hooks":
+			json.NewEncoder(w).Encode(hooks)
+		case strings.HasPrefix(r.URL.Path, "/api/v3/admin/hooks"):
+			json.NewEncoder(w).Encode(map[string]interface{}{"id": 7})
+		default:
+			webhooktest.UnexpectedRequest(t, w, r)
+		}
+	})
+}
+
+func TestGlobalHookAdoption(t *testing.T) {
+	hooks := []map[string]interface{}{
+		{"id": 1, "name": "web", "active": true, "events": []string{"organization", "user"}, "config": map[string]interface{}{"url": "https://other.example.com/hook", "content_type": "json", "insecure_ssl": "0"}},
+		{"id": 2, "name": "web", "active": true, "events": []string{"organization", "user"}, "config": map[string]interface{}{"url": "https://hooks.example.com/hook", "content_type": "json", "insecure_ssl": "0"}},
+	}
+	tests := []struct {
+		name          string
+		policy        string
+		trackedHookID string
+		wantConflict  bool
+		wantHookID    string
+		wantAdopted   string
+		wantMutations []string
+	}{
+		{name: "adopt", policy: "Adopt", wantHookID: "2", wantAdopted: "2"},
+		{name: "fail if exists", policy: "FailIfExists", wantConflict: true},
+		// the tracked hook is updated even when its url changed, and the hook of the other receiver is left alone
+		{name: "tracked", policy: "FailIfExists", trackedHookID: "1", wantHookID: "7", wantMutations: []string{"PATCH /api/v3/admin/hooks/1"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := newFakeGlobalHooksServer(t, hooks)
+			git, err := newClient(context.TODO(), oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "token"}), server.URL+"/api/v3/")
+			if err != nil {
+				t.Fatal(err)
+			}
+			gitHubGlobalHook := &redhatcopv1alpha1.GitHubGlobalHook{}
+			gitHubGlobalHook.Spec = redhatcopv1alpha1.GitHubGlobalHookSpec{
+				WebhookURL:     "https://hooks.example.com/hook",
+				Events:         []string{"user", "organization"},
+				ContentType:    "json",
+				Active:         true,
+				AdoptionPolicy: test.policy,
+			}
+			gitHubGlobalHook.Status.HookID = test.trackedHookID
+			// the hooks have no secret, so they are in sync once adopted
+			gitHubGlobalHook.SetAppliedWebhookSecret("")
+
+			webHook := &GlobalHook{gitHubGlobalHook: gitHubGlobalHook, git: git}
+			err = webHook.Reconcile(context.TODO())
+			var conflict *redhatcopv1alpha1.HookConflictError
+			if errors.As(err, &conflict) != test.wantConflict || (err != nil && !test.wantConflict) {
+				t.Fatalf("Reconcile() error = %v, want conflict %v", err, test.wantConflict)
+			}
+			if mutations := server.Mutations(); !reflect.DeepEqual(mutations, append([]string{}, test.wantMutations...)) {
+				t.Errorf("requests = %v, want %v", mutations, test.wantMutations)
+			}
+			if gitHubGlobalHook.Status.HookID != test.wantHookID {
+				t.Errorf("hookID = %q, want %q", gitHubGlobalHook.Status.HookID, test.wantHookID)
+			}
+			if webHook.AdoptedHookID() != test.wantAdopted {
+				t.Errorf("adopted hook = %q, want %q", webHook.AdoptedHookID(), test.wantAdopted)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package github
+
+import (
+	"errors"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1"
+)
+
+func init() {
+	redhatcopv1alpha1.RegisterProvider(&provider{})
+}
+
+// provider registers github with the GitWebhook controller
+type provider struct{}
+
+func (p *provider) Name() string {
+	return "gitHub"
+}
+
+func (p *provider) ServerConfig(spec *redhatcopv1alpha1.GitWebhookSpec) redhatcopv1alpha1.ServerConfig {
+	if spec.GitHub == nil {
+		return nil
+	}
+	return spec.GitHub
+}
+
+func (p *provider) NewWebHook(gitWebhook *redhatcopv1alpha1.GitWebhook) redhatcopv1alpha1.WebHook {
+	return FromGitWebhook(gitWebhook)
+}
+
+func (p *provider) CredentialKeys() [][]string {
+	return [][]string{{"token"}, {"appID", "privateKey"}}
+}
+
+func (p *provider) Events() []string {
+	return nil
+}
+
+func (p *provider) CanonicalEvents() map[string][]string {
+	return map[string][]string{
+		"push":         {"push"},
+		"tag":          {"create"},
+		"pull_request": {"pull_request"},
+		"issue":        {"issues"},
+		"comment":      {"issue_comment", "pull_request_review_comment", "commit_comment"},
+		"release":      {"release"},
+		"pipeline":     {"workflow_run"},
+		"deployment":   {"deployment"},
+		"wiki":         {"gollum"},
+	}
+}
+
+func (p *provider) SupportsOwnerWebHooks() bool {
+	return true
+}
+
+// ValidateSpec rejects the Ignore adoption policy, github refuses to create a webhook whose url is already used by another webhook of the repository or organization
+func (p *provider) ValidateSpec(spec *redhatcopv1alpha1.GitWebhookSpec) error {
+	if spec.AdoptionPolicy == "Ignore" {
+		return errors.New("adoptionPolicy Ignore is not supported by github, which does not allow two webhooks with the same url")
+	}
+	return nil
+}
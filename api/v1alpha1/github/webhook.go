@@ -4,21 +4,30 @@ import (
 	"context"
 	"net/url"
 	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/google/go-github/v48/github"
 	redhatcopv1alpha1 "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1"
 	"golang.org/x/oauth2"
+	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 type GitHubWebHook struct {
 	gitWebhook *redhatcopv1alpha1.GitWebhook
 	git        *github.Client
+	applied    *redhatcopv1alpha1.AppliedWebHook
+	redhatcopv1alpha1.HookAdoption
 }
 
 var web string = "web"
 
 var _ redhatcopv1alpha1.WebHook = &GitHubWebHook{}
+var _ redhatcopv1alpha1.DriftDetector = &GitHubWebHook{}
+var _ redhatcopv1alpha1.AppliedWebHookReporter = &GitHubWebHook{}
+var _ redhatcopv1alpha1.HookAdopter = &GitHubWebHook{}
 
 func FromGitWebhook(gitwebhook *redhatcopv1alpha1.GitWebhook) *GitHubWebHook {
 	return &GitHubWebHook{
@@ -37,14 +46,25 @@ func (m *GitHubWebHook) toWebhook(ctx context.Context) (*github.Hook, error) {
 	if m.gitWebhook.Spec.InsecureSSL {
 		insecure = "1"
 	}
+	webhookURL, err := m.gitWebhook.GetWebhookURL(ctx)
+	if err != nil {
+		log.Error(err, "unable to retrieve webhook url")
+		return nil, err
+	}
+	events, err := m.gitWebhook.GetNativeEvents()
+	if err != nil {
+		log.Error(err, "unable to translate events")
+		return nil, err
+	}
+	sort.Strings(events)
 	hook := github.Hook{
-		Events: m.gitWebhook.Spec.Events,
+		Events: events,
 		Active: &m.gitWebhook.Spec.Active,
 		Name:   &web,
 		Config: map[string]interface{}{
 			"content_type": m.gitWebhook.Spec.ContentType,
 			"insecure_ssl": insecure,
-			"url":          m.gitWebhook.Spec.WebhookURL,
+			"url":          webhookURL,
 			"secret":       secret,
 		},
 	}
@@ -56,15 +76,38 @@ func (m *GitHubWebHook) getClient(ctx context.Context) (*github.Client, error) {
 		return m.git, nil
 	}
 	log := log.FromContext(ctx)
-	token, err := m.gitWebhook.GetGitCredential(ctx, m.gitWebhook.Spec.GitHub)
+	secret, err := m.gitWebhook.GetGitCredentialSecret(ctx, m.gitWebhook.Spec.GitHub)
 	if err != nil {
 		log.Error(err, "Unable to retrieve github credential", "secret", m.gitWebhook.Spec.GitHub.GitServerCredentials.Name)
 		return nil, err
 	}
+	ts, err := m.getTokenSource(ctx, secret)
+	if err != nil {
+		log.Error(err, "Unable to create github token source", "secret", m.gitWebhook.Spec.GitHub.GitServerCredentials.Name)
+		return nil, err
+	}
 
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: token},
-	)
+	git, err := newClient(ctx, ts, m.gitWebhook.Spec.GitHub.GitHubAPIServerURL)
+	if err != nil {
+		log.Error(err, "Unable to parse github url", "url", m.gitWebhook.Spec.GitHub.GitHubAPIServerURL)
+		return nil, err
+	}
+	m.git = git
+	return git, nil
+}
+
+// getTokenSource returns a static token source for personal access tokens, the secret must otherwise contain the credentials of a github app
+func (m *GitHubWebHook) getTokenSource(ctx context.Context, secret *corev1.Secret) (oauth2.TokenSource, error) {
+	if token, found := secret.Data["token"]; found {
+		return oauth2.StaticTokenSource(
+			&oauth2.Token{AccessToken: string(token)},
+		), nil
+	}
+	return appTokenSource(ctx, secret, m.gitWebhook.Spec.GitHub.GitHubAPIServerURL, m.gitWebhook.Spec.RepositoryOwner, m.gitWebhook.Spec.RepositoryName)
+}
+
+// newClient creates a github client, the default api.github.com url is used when baseURL is empty
+func newClient(ctx context.Context, ts oauth2.TokenSource, baseURL string) (*github.Client, error) {
 	//debug client, might be useful
 	//tc := &oauth2.Transport{Source: ts, Base: dbg.New()}
 	//git := github.NewClient(&http.Client{Transport: tc})
@@ -73,10 +116,14 @@ func (m *GitHubWebHook) getClient(ctx context.Context) (*github.Client, error) {
 	tc := oauth2.NewClient(ctx, ts)
 	git := github.NewClient(tc)
 
-	if m.gitWebhook.Spec.GitHub.GitHubAPIServerURL != "" {
-		git.BaseURL, err = url.Parse(m.gitWebhook.Spec.GitHub.GitHubAPIServerURL)
+	if baseURL != "" {
+		// the client requires a trailing slash to resolve the api paths
+		if !strings.HasSuffix(baseURL, "/") {
+			baseURL = baseURL + "/"
+		}
+		var err error
+		git.BaseURL, err = url.Parse(baseURL)
 		if err != nil {
-			log.Error(err, "Unable to parse github url", "url", m.gitWebhook.Spec.GitHub.GitHubAPIServerURL)
 			return nil, err
 		}
 	}
@@ -90,19 +137,25 @@ func (m *GitHubWebHook) getHook(ctx context.Context) (*github.Hook, bool, error)
 		log.Error(err, "unable to create github client")
 		return nil, false, err
 	}
+	webhookURL, err := m.gitWebhook.GetWebhookURL(ctx)
+	if err != nil {
+		log.Error(err, "unable to retrieve webhook url")
+		return nil, false, err
+	}
 
 	opt := &github.ListOptions{
 		PerPage: 100,
 	}
 
+	finder := redhatcopv1alpha1.NewHookFinder[*github.Hook](m.gitWebhook, &m.HookAdoption, webhookURL)
 	for {
-		hooks, response, err := git.Repositories.ListHooks(ctx, m.gitWebhook.Spec.RepositoryOwner, m.gitWebhook.Spec.RepositoryName, opt)
+		hooks, response, err := m.listHooks(ctx, git, opt)
 		if err != nil || IsNotFound(response) {
-			log.Error(err, "unable to list hooks", "for repo", m.gitWebhook.Spec.RepositoryOwner+"/"+m.gitWebhook.Spec.RepositoryName)
+			log.Error(err, "unable to list hooks", "for", m.hookOwner())
 			return nil, false, err
 		}
 		for _, hook := range hooks {
-			if hook.Config["url"] == m.gitWebhook.Spec.WebhookURL {
+			if hookURL, _ := hook.Config["url"].(string); finder.Owned(hook, strconv.FormatInt(hook.GetID(), 10), hookURL) {
 				//found
 				return hook, true, nil
 			}
@@ -112,7 +165,47 @@ func (m *GitHubWebHook) getHook(ctx context.Context) (*github.Hook, bool, error)
 		}
 		opt.Page = response.NextPage
 	}
-	return nil, false, nil
+	return finder.Claim()
+}
+
+// isOrganizationHook returns true when the hook must be created on the organization, which happens when no repository is specified
+func (m *GitHubWebHook) isOrganizationHook() bool {
+	return m.gitWebhook.Spec.OwnerType == "organization" && m.gitWebhook.Spec.RepositoryName == ""
+}
+
+func (m *GitHubWebHook) hookOwner() string {
+	if m.isOrganizationHook() {
+		return m.gitWebhook.Spec.RepositoryOwner
+	}
+	return m.gitWebhook.Spec.RepositoryOwner + "/" + m.gitWebhook.Spec.RepositoryName
+}
+
+func (m *GitHubWebHook) listHooks(ctx context.Context, git *github.Client, opt *github.ListOptions) ([]*github.Hook, *github.Response, error) {
+	if m.isOrganizationHook() {
+		return git.Organizations.ListHooks(ctx, m.gitWebhook.Spec.RepositoryOwner, opt)
+	}
+	return git.Repositories.ListHooks(ctx, m.gitWebhook.Spec.RepositoryOwner, m.gitWebhook.Spec.RepositoryName, opt)
+}
+
+func (m *GitHubWebHook) createHook(ctx context.Context, git *github.Client, hook *github.Hook) (*github.Hook, *github.Response, error) {
+	if m.isOrganizationHook() {
+		return git.Organizations.CreateHook(ctx, m.gitWebhook.Spec.RepositoryOwner, hook)
+	}
+	return git.Repositories.CreateHook(ctx, m.gitWebhook.Spec.RepositoryOwner, m.gitWebhook.Spec.RepositoryName, hook)
+}
+
+func (m *GitHubWebHook) editHook(ctx context.Context, git *github.Client, id int64, hook *github.Hook) (*github.Hook, *github.Response, error) {
+	if m.isOrganizationHook() {
+		return git.Organizations.EditHook(ctx, m.gitWebhook.Spec.RepositoryOwner, id, hook)
+	}
+	return git.Repositories.EditHook(ctx, m.gitWebhook.Spec.RepositoryOwner, m.gitWebhook.Spec.RepositoryName, id, hook)
+}
+
+func (m *GitHubWebHook) deleteHook(ctx context.Context, git *github.Client, id int64) (*github.Response, error) {
+	if m.isOrganizationHook() {
+		return git.Organizations.DeleteHook(ctx, m.gitWebhook.Spec.RepositoryOwner, id)
+	}
+	return git.Repositories.DeleteHook(ctx, m.gitWebhook.Spec.RepositoryOwner, m.gitWebhook.Spec.RepositoryName, id)
 }
 
 func IsNotFound(response *github.Response) bool {
@@ -135,6 +228,16 @@ func (m *GitHubWebHook) isEquivalent(ctx context.Context) (bool, error) {
 	if !found {
 		return false, nil
 	}
+	m.gitWebhook.SetLastDelivery(toDelivery(actualHook.LastResponse))
+	secret, err := m.gitWebhook.GetWebhookSecret(ctx)
+	if err != nil {
+		log.Error(err, "unable to retrieve webhook secret")
+		return false, err
+	}
+	// the secret is not returned by the git server, a changed secret is detected with the fingerprint of the applied secret
+	if !m.gitWebhook.IsWebhookSecretApplied(secret) {
+		return false, nil
+	}
 
 	actualHook.CreatedAt = nil
 	actualHook.UpdatedAt = nil
@@ -144,6 +247,7 @@ func (m *GitHubWebHook) isEquivalent(ctx context.Context) (bool, error) {
 	actualHook.LastResponse = nil
 	actualHook.PingURL = nil
 	actualHook.TestURL = nil
+	sort.Strings(actualHook.Events)
 
 	delete(actualHook.Config, "secret")
 	delete(desiredHook.Config, "secret")
@@ -152,6 +256,21 @@ func (m *GitHubWebHook) isEquivalent(ctx context.Context) (bool, error) {
 
 }
 
+// toDelivery converts the last response of a hook, for example {"code": 503, "status": "active", "message": "Service Unavailable"}, or {"code": null, "status": "unused", "message": null} when nothing was delivered yet
+func toDelivery(lastResponse map[string]interface{}) *redhatcopv1alpha1.WebhookDelivery {
+	if lastResponse == nil {
+		return nil
+	}
+	delivery := &redhatcopv1alpha1.WebhookDelivery{}
+	if code, ok := lastResponse["code"].(float64); ok {
+		delivery.Code = int(code)
+	}
+	delivery.Status, _ = lastResponse["status"].(string)
+	delivery.Message, _ = lastResponse["message"].(string)
+	delivery.Failed = delivery.Status != "unused" && (delivery.Code < 200 || delivery.Code >= 300)
+	return delivery
+}
+
 func (m *GitHubWebHook) reconcile(ctx context.Context) error {
 	log := log.FromContext(ctx)
 	equivalent, err := m.isEquivalent(ctx)
@@ -159,10 +278,64 @@ func (m *GitHubWebHook) reconcile(ctx context.Context) error {
 		log.Error(err, "unable to determine if desired state is equal to actual state")
 		return err
 	}
-	if equivalent {
-		return nil
+	if !equivalent {
+		err = m.createOrUpdateWebhook(ctx)
+		if err != nil {
+			return err
+		}
 	}
-	return m.createOrUpdateWebhook(ctx)
+	return m.recordAppliedWebHook(ctx)
+}
+
+// recordAppliedWebHook records the webhook sent to or found in sync on github
+func (m *GitHubWebHook) recordAppliedWebHook(ctx context.Context) error {
+	log := log.FromContext(ctx)
+	hook, err := m.toWebhook(ctx)
+	if err != nil {
+		log.Error(err, "error to convert to github hook")
+		return err
+	}
+	repositoryID, err := m.getRepositoryID(ctx)
+	if err != nil {
+		log.Error(err, "unable to retrieve repository id", "for", m.hookOwner())
+		return err
+	}
+	contentType, _ := hook.Config["content_type"].(string)
+	insecureSSL := hook.Config["insecure_ssl"] == "1"
+	m.applied = &redhatcopv1alpha1.AppliedWebHook{
+		RepositoryID: repositoryID,
+		Events:       hook.Events,
+		Settings: redhatcopv1alpha1.AppliedWebhookSettings{
+			ContentType: contentType,
+			Active:      hook.Active,
+			InsecureSSL: &insecureSSL,
+		},
+	}
+	return nil
+}
+
+// getRepositoryID returns the id of the repository, or of the organization for the organization webhooks.
+// The repository of a GitWebhook cannot be changed, so the id recorded in the status is reused
+func (m *GitHubWebHook) getRepositoryID(ctx context.Context) (string, error) {
+	if m.gitWebhook.Status.RepositoryID != "" {
+		return m.gitWebhook.Status.RepositoryID, nil
+	}
+	git, err := m.getClient(ctx)
+	if err != nil {
+		return "", err
+	}
+	if m.isOrganizationHook() {
+		organization, _, err := git.Organizations.Get(ctx, m.gitWebhook.Spec.RepositoryOwner)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatInt(organization.GetID(), 10), nil
+	}
+	repository, _, err := git.Repositories.Get(ctx, m.gitWebhook.Spec.RepositoryOwner, m.gitWebhook.Spec.RepositoryName)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(repository.GetID(), 10), nil
 }
 
 func (m *GitHubWebHook) createOrUpdateWebhook(ctx context.Context) error {
@@ -184,14 +357,15 @@ func (m *GitHubWebHook) createOrUpdateWebhook(ctx context.Context) error {
 	}
 	if !found {
 		//we need to create
-		_, _, err := git.Repositories.CreateHook(ctx, m.gitWebhook.Spec.RepositoryOwner, m.gitWebhook.Spec.RepositoryName, newHook)
+		hook, _, err := m.createHook(ctx, git, newHook)
 		if err != nil {
 			log.Error(err, "unable to create new hook")
 			return err
 		}
+		m.gitWebhook.SetOwnedHook(strconv.FormatInt(hook.GetID(), 10))
 	} else {
 		//we need to update
-		_, _, err = git.Repositories.EditHook(ctx, m.gitWebhook.Spec.RepositoryOwner, m.gitWebhook.Spec.RepositoryName, *actualHook.ID, newHook)
+		_, _, err = m.editHook(ctx, git, *actualHook.ID, newHook)
 		if err != nil {
 			log.Error(err, "unable to update github webhook")
 			return err
@@ -215,7 +389,7 @@ func (m *GitHubWebHook) deleteIfExists(ctx context.Context) error {
 		log.Error(err, "error get github client")
 		return err
 	}
-	_, err = git.Repositories.DeleteHook(ctx, m.gitWebhook.Spec.RepositoryOwner, m.gitWebhook.Spec.RepositoryName, *hook.ID)
+	_, err = m.deleteHook(ctx, git, *hook.ID)
 	if err != nil {
 		log.Error(err, "unable to delete webhook")
 		return err
@@ -230,3 +404,11 @@ func (m *GitHubWebHook) Reconcile(ctx context.Context) error {
 func (m *GitHubWebHook) Delete(ctx context.Context) error {
 	return m.deleteIfExists(ctx)
 }
+
+func (m *GitHubWebHook) IsEquivalent(ctx context.Context) (bool, error) {
+	return m.isEquivalent(ctx)
+}
+
+func (m *GitHubWebHook) AppliedWebHook() *redhatcopv1alpha1.AppliedWebHook {
+	return m.applied
+}
@@ -0,0 +1,235 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v48/github"
+	redhatcopv1alpha1 "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1"
+	"github.com/redhat-cop/gitwebhook-operator/api/v1alpha1/webhooktest"
+	"golang.org/x/oauth2"
+)
+
+// fakeServer serves the organizations, the repositories and their hooks, one hook per page so that the listings are paginated
+type fakeServer struct {
+	*webhooktest.Server
+	// hooks by hooks path, for example /api/v3/orgs/team/hooks
+	hooks  map[string][]*github.Hook
+	lastID int64
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	s := &fakeServer{hooks: map[string][]*github.Hook{}, lastID: 100}
+	s.Server = webhooktest.NewServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		hooksPath, id, isHooks := strings.Cut(r.URL.Path, "/hooks")
+		hooksPath += "/hooks"
+		id = strings.TrimPrefix(id, "/")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v3/orgs/team":
+			json.NewEncoder(w).Encode(&github.Organization{ID: github.Int64(7)})
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v3/repos/team/app":
+			json.NewEncoder(w).Encode(&github.Repository{ID: github.Int64(42)})
+		case !isHooks:
+			webhooktest.UnexpectedRequest(t, w, r)
+		case r.Method == http.MethodGet && id == "":
+			page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+			if page == 0 {
+				page = 1
+			}
+			hooks := []*github.Hook{}
+			if page <= len(s.hooks[hooksPath]) {
+				hooks = append(hooks, s.hooks[hooksPath][page-1])
+			}
+			if page < len(s.hooks[hooksPath]) {
+				w.Header().Set("Link", "<"+s.URL+hooksPath+"?page="+strconv.Itoa(page+1)+">; rel=\"next\"")
+			}
+			json.NewEncoder(w).Encode(hooks)
+		case r.Method == http.MethodPost && id == "":
+			created := &github.Hook{}
+			json.NewDecoder(r.Body).Decode(created)
+			s.lastID++
+			created.ID = github.Int64(s.lastID)
+			// github does not return the secret
+			delete(created.Config, "secret")
+			s.hooks[hooksPath] = append(s.hooks[hooksPath], created)
+			json.NewEncoder(w).Encode(created)
+		case r.Method == http.MethodPatch:
+			for i, existing := range s.hooks[hooksPath] {
+				if strconv.FormatInt(existing.GetID(), 10) == id {
+					updated := &github.Hook{}
+					json.NewDecoder(r.Body).Decode(updated)
+					updated.ID = existing.ID
+					delete(updated.Config, "secret")
+					s.hooks[hooksPath][i] = updated
+					json.NewEncoder(w).Encode(updated)
+					return
+				}
+			}
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodDelete:
+			for i, existing := range s.hooks[hooksPath] {
+				if strconv.FormatInt(existing.GetID(), 10) == id {
+					s.hooks[hooksPath] = append(s.hooks[hooksPath][:i], s.hooks[hooksPath][i+1:]...)
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+			}
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			webhooktest.UnexpectedRequest(t, w, r)
+		}
+	})
+	return s
+}
+
+func newTestWebHook(t *testing.T, server *fakeServer, gitWebhook *redhatcopv1alpha1.GitWebhook) *GitHubWebHook {
+	git, err := newClient(context.TODO(), oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "token"}), server.URL+"/api/v3/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &GitHubWebHook{gitWebhook: gitWebhook, git: git}
+}
+
+func newTestGitWebhook(serverURL string, ownerType string, repositoryName string) *redhatcopv1alpha1.GitWebhook {
+	gitWebhook := &redhatcopv1alpha1.GitWebhook{}
+	gitWebhook.Name = "hook"
+	gitWebhook.Namespace = "default"
+	gitWebhook.Spec = redhatcopv1alpha1.GitWebhookSpec{
+		GitHub:          &redhatcopv1alpha1.GitHubServerConfig{GitHubAPIServerURL: serverURL + "/api/v3/"},
+		OwnerType:       ownerType,
+		RepositoryOwner: "team",
+		RepositoryName:  repositoryName,
+		WebhookURL:      "https://hooks.example.com/app",
+		Events:          []string{"push"},
+		ContentType:     "json",
+		Active:          true,
+	}
+	// the hooks have no secret
+	gitWebhook.SetAppliedWebhookSecret("")
+	return gitWebhook
+}
+
+func TestOrganizationHookLifecycle(t *testing.T) {
+	const hooksPath = "/api/v3/orgs/team/hooks"
+	server := newFakeServer(t)
+	server.hooks[hooksPath] = []*github.Hook{
+		{ID: github.Int64(1), Name: github.String("web"), Events: []string{"push"}, Config: map[string]interface{}{"url": "https://other.example.com/1"}},
+		{ID: github.Int64(2), Name: github.String("web"), Events: []string{"push"}, Config: map[string]interface{}{"url": "https://other.example.com/2"}},
+	}
+	gitWebhook := newTestGitWebhook(server.URL, "organization", "")
+
+	webhooktest.RunLifecycle(t, server.Server, func() redhatcopv1alpha1.WebHook { return newTestWebHook(t, server, gitWebhook) },
+		// the hooks of the other receivers are listed over several pages
+		webhooktest.Step{
+			Name:      "create",
+			Mutations: []string{"POST " + hooksPath},
+			Check: func(t *testing.T, webHook redhatcopv1alpha1.WebHook) {
+				hooks := server.hooks[hooksPath]
+				if len(hooks) != 3 || hooks[2].Config["url"] != "https://hooks.example.com/app" {
+					t.Fatalf("create: hook not created as expected: %+v", hooks)
+				}
+				if gitWebhook.Status.HookID != "101" {
+					t.Fatalf("create: hookID = %q, want 101", gitWebhook.Status.HookID)
+				}
+				if applied := webHook.(*GitHubWebHook).AppliedWebHook(); applied == nil || applied.RepositoryID != "7" {
+					t.Fatalf("create: applied webhook = %+v, want organization 7", applied)
+				}
+			},
+		},
+		// the owned hook is found on the last page
+		webhooktest.Step{Name: "resync"},
+		webhooktest.Step{
+			Name:      "update",
+			Change:    func() { gitWebhook.Spec.Events = []string{"push", "repository"} },
+			Mutations: []string{"PATCH " + hooksPath + "/101"},
+			Check: func(t *testing.T, _ redhatcopv1alpha1.WebHook) {
+				hooks := server.hooks[hooksPath]
+				if len(hooks) != 3 || !reflect.DeepEqual(hooks[2].Events, []string{"push", "repository"}) || hooks[2].GetID() != 101 {
+					t.Fatalf("update: hook not updated as expected: %+v", hooks[2])
+				}
+			},
+		},
+		// the hooks of the other receivers are left alone
+		webhooktest.Step{
+			Name:      "delete",
+			Delete:    true,
+			Mutations: []string{"DELETE " + hooksPath + "/101"},
+			Check: func(t *testing.T, _ redhatcopv1alpha1.WebHook) {
+				hooks := server.hooks[hooksPath]
+				if len(hooks) != 2 || hooks[0].GetID() != 1 || hooks[1].GetID() != 2 {
+					t.Fatalf("delete: unexpected remaining hooks %+v", hooks)
+				}
+			},
+		},
+	)
+}
+
+func TestHookOwnerSelection(t *testing.T) {
+	tests := []struct {
+		name           string
+		ownerType      string
+		repositoryName string
+		wantMutations  []string
+	}{
+		// ownerType defaults to organization, a repository hook is managed whenever the repository is named
+		{name: "organization repository", ownerType: "organization", repositoryName: "app", wantMutations: []string{"POST /api/v3/repos/team/app/hooks"}},
+		{name: "user repository", ownerType: "user", repositoryName: "app", wantMutations: []string{"POST /api/v3/repos/team/app/hooks"}},
+		{name: "organization", ownerType: "organization", wantMutations: []string{"POST /api/v3/orgs/team/hooks"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := newFakeServer(t)
+			gitWebhook := newTestGitWebhook(server.URL, test.ownerType, test.repositoryName)
+			webhooktest.RunLifecycle(t, server.Server, func() redhatcopv1alpha1.WebHook { return newTestWebHook(t, server, gitWebhook) },
+				webhooktest.Step{Name: "create", Mutations: test.wantMutations},
+			)
+		})
+	}
+}
+
+func TestToDelivery(t *testing.T) {
+	tests := []struct {
+		name         string
+		lastResponse map[string]interface{}
+		want         *redhatcopv1alpha1.WebhookDelivery
+	}{
+		{
+			name:         "no last response",
+			lastResponse: nil,
+			want:         nil,
+		},
+		{
+			name:         "nothing delivered yet",
+			lastResponse: map[string]interface{}{"code": nil, "status": "unused", "message": nil},
+			want:         &redhatcopv1alpha1.WebhookDelivery{Status: "unused"},
+		},
+		{
+			name:         "delivered",
+			lastResponse: map[string]interface{}{"code": float64(200), "status": "active", "message": "OK"},
+			want:         &redhatcopv1alpha1.WebhookDelivery{Code: 200, Status: "active", Message: "OK"},
+		},
+		{
+			name:         "receiver error",
+			lastResponse: map[string]interface{}{"code": float64(503), "status": "active", "message": "Service Unavailable"},
+			want:         &redhatcopv1alpha1.WebhookDelivery{Code: 503, Status: "active", Message: "Service Unavailable", Failed: true},
+		},
+		{
+			name:         "receiver unreachable",
+			lastResponse: map[string]interface{}{"code": nil, "status": "timeout", "message": "timed out"},
+			want:         &redhatcopv1alpha1.WebhookDelivery{Status: "timeout", Message: "timed out", Failed: true},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := toDelivery(test.lastResponse); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("toDelivery() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
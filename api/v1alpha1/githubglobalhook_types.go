@@ -0,0 +1,183 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GitHubGlobalHookSpec defines the desired state of GitHubGlobalHook
+type GitHubGlobalHookSpec struct {
+
+	// GitHub the configuration to connect to the github enterprise server, the credentials must belong to a site administrator
+	// +kubebuilder:validation:Required
+	GitHub GitHubInstanceServerConfig `json:"gitHub"`
+
+	// WebhookURL The URL of the global webhook to be called
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^https?:\/\/(?:www\.)?[-a-zA-Z0-9@:%._\+~#=]{1,256}\.[a-zA-Z0-9()]{1,6}\b(?:[-a-zA-Z0-9()@:%_\+.~#?&\/=]*)$`
+	WebhookURL string `json:"webhookURL,omitempty"`
+
+	// InsecureSSL whether to not verify the certificate of the server serving the global webhook
+	InsecureSSL bool `json:"insecureSSL,omitempty"`
+
+	// WebhookSecret The secret to be used in the global webhook callbacks. The key "secret" will be used to retrieve the secret/token
+	WebhookSecret corev1.SecretReference `json:"webhookSecret,omitempty"`
+
+	// Events The list of events that this global webhook should be notified for
+	// +listType=set
+	// +kubebuilder:validation:items:Enum="user";"organization"
+	// +kubebuilder:default={"user","organization"}
+	Events []string `json:"events,omitempty"`
+
+	// ContentType the content type of the global webhook playload
+	// +kubebuilder:default="json"
+	ContentType string `json:"content,omitempty"`
+
+	// Active whether this global webhook should be active
+	// +kubebuilder:default=true
+	Active bool `json:"active,omitempty"`
+
+	// AdoptionPolicy what to do with a global webhook that has the url of this GitHubGlobalHook but was not created by it: take it over (Adopt) or report a conflict (FailIfExists).
+	// The global webhook created or adopted is tracked by its id in the status, an adoption is reported with a WebhookAdopted event. Ignore is not supported, as github does not allow two global webhooks with the same url
+	// +kubebuilder:validation:Enum=Adopt;FailIfExists
+	// +kubebuilder:default=Adopt
+	AdoptionPolicy string `json:"adoptionPolicy,omitempty"`
+}
+
+// GitHubInstanceServerConfig the configuration to connect to a github enterprise server from a cluster scoped resource
+type GitHubInstanceServerConfig struct {
+	// GitHubAPIServerURL the url of the github enterprise server api, for example https://github.example.com/api/v3/
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^https?:\/\/(?:www\.)?[-a-zA-Z0-9@:%._\+~#=]{1,256}\.[a-zA-Z0-9()]{1,6}\b(?:[-a-zA-Z0-9()@:%_\+.~#?&\/=]*)$`
+	GitHubAPIServerURL string `json:"gitHubAPIServerURL,omitempty"`
+	// GitServerCredentials credentials to use when authenticating to the git server, must contain a "token" key
+	// +kubebuilder:validation:Required
+	GitServerCredentials corev1.SecretReference `json:"gitServerCredentials,omitempty"`
+}
+
+// GitHubGlobalHookStatus defines the observed state of GitHubGlobalHook
+type GitHubGlobalHookStatus struct {
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+	// HookID the id on the git server of the global webhook created or adopted by this GitHubGlobalHook
+	HookID string `json:"hookID,omitempty"`
+	// WebhookSecretHash a salted sha256 of the webhook secret last applied to the git server, the git server does not return the secret so it is used to detect secret changes
+	WebhookSecretHash string `json:"webhookSecretHash,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`
+//+kubebuilder:printcolumn:name="Message",type=string,priority=1,JSONPath=`.status.conditions[?(@.type=="Ready")].message`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// GitHubGlobalHook is the Schema for the githubglobalhooks API
+type GitHubGlobalHook struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GitHubGlobalHookSpec   `json:"spec,omitempty"`
+	Status GitHubGlobalHookStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// GitHubGlobalHookList contains a list of GitHubGlobalHook
+type GitHubGlobalHookList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GitHubGlobalHook `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GitHubGlobalHook{}, &GitHubGlobalHookList{})
+}
+
+func (m *GitHubGlobalHook) GetConditions() []metav1.Condition {
+	return m.Status.Conditions
+}
+
+func (m *GitHubGlobalHook) SetConditions(conditions []metav1.Condition) {
+	m.Status.Conditions = conditions
+}
+
+func (m *GitHubGlobalHook) GetWebhookSecret(ctx context.Context) (string, error) {
+	if m.Spec.WebhookSecret.Name == "" {
+		return "", nil
+	}
+	return getSecretKey(ctx, m.Spec.WebhookSecret, "secret")
+}
+
+func (m *GitHubGlobalHook) GetGitCredential(ctx context.Context) (string, error) {
+	return getSecretKey(ctx, m.Spec.GitHub.GitServerCredentials, "token")
+}
+
+// GetReferencedSecrets returns the secrets read to reconcile the global webhook
+func (m *GitHubGlobalHook) GetReferencedSecrets() []corev1.SecretReference {
+	return []corev1.SecretReference{m.Spec.WebhookSecret, m.Spec.GitHub.GitServerCredentials}
+}
+
+// IsOwnedHook returns whether the global webhook with the given id was created or adopted by the GitHubGlobalHook
+func (m *GitHubGlobalHook) IsOwnedHook(hookID string) bool {
+	return hookID != "" && hookID == m.Status.HookID
+}
+
+// GetHookID returns the id of the global webhook created or adopted by the GitHubGlobalHook
+func (m *GitHubGlobalHook) GetHookID() string {
+	return m.Status.HookID
+}
+
+// SetOwnedHook records the id of the global webhook created or adopted by the GitHubGlobalHook
+func (m *GitHubGlobalHook) SetOwnedHook(hookID string) {
+	m.Status.HookID = hookID
+}
+
+// MatchesWebhookURL returns whether a global webhook found on the git server has the url of the GitHubGlobalHook
+func (m *GitHubGlobalHook) MatchesWebhookURL(hookURL string, webhookURL string) bool {
+	return hookURL == webhookURL
+}
+
+// ClaimHook decides as per the adoption policy whether a global webhook that has the url of the GitHubGlobalHook but was not created by it is managed by the GitHubGlobalHook
+func (m *GitHubGlobalHook) ClaimHook(hookID string) (bool, error) {
+	if m.IsOwnedHook(hookID) {
+		return true, nil
+	}
+	claimed, err := claimHook(m.Spec.AdoptionPolicy, !m.DeletionTimestamp.IsZero(), m.Status.HookID, hookID)
+	if claimed {
+		m.SetOwnedHook(hookID)
+	}
+	return claimed, err
+}
+
+// SetAppliedWebhookSecret records the fingerprint of the webhook secret applied to the git server
+func (m *GitHubGlobalHook) SetAppliedWebhookSecret(secret string) {
+	m.Status.WebhookSecretHash = saltedHash(m.GetUID(), secret)
+}
+
+// IsWebhookSecretApplied returns whether the webhook secret is the one last applied to the git server
+func (m *GitHubGlobalHook) IsWebhookSecretApplied(secret string) bool {
+	return saltedHash(m.GetUID(), secret) == m.Status.WebhookSecretHash
+}
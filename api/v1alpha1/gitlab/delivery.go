@@ -0,0 +1,91 @@
+package gitlab
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1"
+	"github.com/xanzy/go-gitlab"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// hookEvent is a delivery of a hook, see https://docs.gitlab.com/ee/api/project_webhooks.html#list-webhook-events
+type hookEvent struct {
+	ResponseStatus string `json:"response_status"`
+}
+
+// disabledUntilPrefix starts the message of a delivery that reports a disabled hook, the other messages come from the last event
+const disabledUntilPrefix = "disabled until "
+
+// recordDelivery records the outcome of the last delivery of the hook, from the alert status of the hook and, on the gitlab versions that list them, from its last event.
+// The alert status comes with the listing of the hooks, the last event is an extra request which is only made when readLastEvent is set, otherwise the last event previously recorded is kept
+func (m *GitLabWebHook) recordDelivery(ctx context.Context, hookPath string, fields *hookFields, readLastEvent bool) {
+	var event *hookEvent
+	found := false
+	if readLastEvent {
+		event, found = m.getLastHookEvent(ctx, hookPath)
+	} else {
+		event, found = previousHookEvent(m.gitWebhook.Status.LastDelivery)
+	}
+	if fields.AlertStatus == "" && !found {
+		m.gitWebhook.SetLastDelivery(nil)
+		return
+	}
+	delivery := &redhatcopv1alpha1.WebhookDelivery{
+		Status: fields.AlertStatus,
+		Failed: fields.AlertStatus != "" && fields.AlertStatus != "executable",
+	}
+	if fields.DisabledUntil != nil {
+		delivery.Message = disabledUntilPrefix + fields.DisabledUntil.String()
+	}
+	if found {
+		// the response status is the http status code, or the error when the receiver could not be reached
+		code, err := strconv.Atoi(event.ResponseStatus)
+		if err != nil {
+			delivery.Message = event.ResponseStatus
+		}
+		delivery.Code = code
+		delivery.Failed = delivery.Failed || code < 200 || code >= 300
+	}
+	m.gitWebhook.SetLastDelivery(delivery)
+}
+
+// previousHookEvent returns the last event recorded in the delivery, if any
+func previousHookEvent(delivery *redhatcopv1alpha1.WebhookDelivery) (*hookEvent, bool) {
+	switch {
+	case delivery == nil:
+		return nil, false
+	case delivery.Code != 0:
+		return &hookEvent{ResponseStatus: strconv.Itoa(delivery.Code)}, true
+	case delivery.Message != "" && !strings.HasPrefix(delivery.Message, disabledUntilPrefix):
+		return &hookEvent{ResponseStatus: delivery.Message}, true
+	}
+	return nil, false
+}
+
+// getLastHookEvent returns the last delivery of the hook, the events are not listed by the older gitlab versions, in which case the delivery is only known from the alert status
+func (m *GitLabWebHook) getLastHookEvent(ctx context.Context, hookPath string) (*hookEvent, bool) {
+	log := log.FromContext(ctx)
+	git, err := m.getClient(ctx)
+	if err != nil {
+		log.Error(err, "unable to create gitlab client")
+		return nil, false
+	}
+	req, err := git.NewRequest(http.MethodGet, hookPath+"/events", &gitlab.ListOptions{PerPage: 1}, []gitlab.RequestOptionFunc{gitlab.WithContext(ctx)})
+	if err != nil {
+		log.Error(err, "unable to create request")
+		return nil, false
+	}
+	events := []*hookEvent{}
+	_, err = git.Do(req, &events)
+	if err != nil {
+		log.V(1).Info("unable to list hook events", "error", err.Error())
+		return nil, false
+	}
+	if len(events) == 0 {
+		return nil, false
+	}
+	return events[0], true
+}
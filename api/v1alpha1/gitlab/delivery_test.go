@@ -0,0 +1,117 @@
+package gitlab
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1"
+	"github.com/xanzy/go-gitlab"
+)
+
+func TestRecordDelivery(t *testing.T) {
+	disabledUntil := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name     string
+		fields   hookFields
+		events   string
+		status   int
+		resync   bool
+		previous *redhatcopv1alpha1.WebhookDelivery
+		want     *redhatcopv1alpha1.WebhookDelivery
+	}{
+		{
+			name:   "nothing reported",
+			events: `[]`,
+			status: http.StatusOK,
+			want:   nil,
+		},
+		{
+			name:   "events not listed by the gitlab version",
+			fields: hookFields{AlertStatus: "executable"},
+			status: http.StatusNotFound,
+			want:   &redhatcopv1alpha1.WebhookDelivery{Status: "executable"},
+		},
+		{
+			name:   "delivered",
+			fields: hookFields{AlertStatus: "executable"},
+			events: `[{"response_status": "200"}]`,
+			status: http.StatusOK,
+			want:   &redhatcopv1alpha1.WebhookDelivery{Code: 200, Status: "executable"},
+		},
+		{
+			name:   "receiver error",
+			fields: hookFields{AlertStatus: "executable"},
+			events: `[{"response_status": "500"}]`,
+			status: http.StatusOK,
+			want:   &redhatcopv1alpha1.WebhookDelivery{Code: 500, Status: "executable", Failed: true},
+		},
+		{
+			name:   "receiver unreachable",
+			events: `[{"response_status": "internal error"}]`,
+			status: http.StatusOK,
+			want:   &redhatcopv1alpha1.WebhookDelivery{Message: "internal error", Failed: true},
+		},
+		{
+			name:   "hook disabled",
+			fields: hookFields{AlertStatus: "temporarily_disabled", DisabledUntil: &disabledUntil},
+			status: http.StatusNotFound,
+			want:   &redhatcopv1alpha1.WebhookDelivery{Status: "temporarily_disabled", Message: "disabled until " + disabledUntil.String(), Failed: true},
+		},
+		{
+			name:     "resync keeps the last event",
+			fields:   hookFields{AlertStatus: "temporarily_disabled", DisabledUntil: &disabledUntil},
+			resync:   true,
+			previous: &redhatcopv1alpha1.WebhookDelivery{Code: 500, Status: "executable", Failed: true},
+			want:     &redhatcopv1alpha1.WebhookDelivery{Code: 500, Status: "temporarily_disabled", Message: "disabled until " + disabledUntil.String(), Failed: true},
+		},
+		{
+			name:     "resync keeps the unreachable receiver",
+			fields:   hookFields{AlertStatus: "executable"},
+			resync:   true,
+			previous: &redhatcopv1alpha1.WebhookDelivery{Message: "internal error", Status: "executable", Failed: true},
+			want:     &redhatcopv1alpha1.WebhookDelivery{Message: "internal error", Status: "executable", Failed: true},
+		},
+		{
+			name:     "resync after the hook was re-enabled",
+			fields:   hookFields{AlertStatus: "executable"},
+			resync:   true,
+			previous: &redhatcopv1alpha1.WebhookDelivery{Status: "temporarily_disabled", Message: "disabled until " + disabledUntil.String(), Failed: true},
+			want:     &redhatcopv1alpha1.WebhookDelivery{Status: "executable"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/api/v4/":
+					// the client reads the rate limit of the server when it is created
+					return
+				case "/api/v4/projects/1/hooks/2/events":
+					if test.resync {
+						t.Errorf("unexpected request %s on resync", r.URL.Path)
+					}
+				default:
+					t.Errorf("unexpected request %s", r.URL.Path)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(test.status)
+				w.Write([]byte(test.events))
+			}))
+			defer server.Close()
+			git, err := gitlab.NewClient("token", gitlab.WithBaseURL(server.URL))
+			if err != nil {
+				t.Fatal(err)
+			}
+			m := &GitLabWebHook{gitWebhook: &redhatcopv1alpha1.GitWebhook{}, gitlab: git}
+			m.gitWebhook.Status.LastDelivery = test.previous
+			m.recordDelivery(context.TODO(), "projects/1/hooks/2", &test.fields, !test.resync)
+			if got := m.gitWebhook.Status.LastDelivery; !reflect.DeepEqual(got, test.want) {
+				t.Errorf("LastDelivery = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
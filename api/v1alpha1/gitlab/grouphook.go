@@ -0,0 +1,277 @@
+package gitlab
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1"
+	"github.com/xanzy/go-gitlab"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// groupHook adds to gitlab.GroupHook the fields that the client library does not know about yet
+type groupHook struct {
+	gitlab.GroupHook
+	hookFields
+	MemberEvents bool `json:"member_events"`
+}
+
+// groupHookOptions adds to gitlab.EditGroupHookOptions the fields that the client library does not know about yet, add and edit accept the same options
+type groupHookOptions struct {
+	gitlab.EditGroupHookOptions
+	hookOptionFields
+	MemberEvents *bool `url:"member_events,omitempty" json:"member_events,omitempty"`
+}
+
+// isGroupHook returns true when the hook must be created on the group, which happens when no repository is specified
+func (m *GitLabWebHook) isGroupHook() bool {
+	return m.gitWebhook.Spec.OwnerType == "organization" && m.gitWebhook.Spec.RepositoryName == ""
+}
+
+// RepositoryOwner is the full path of the group
+func (m *GitLabWebHook) groupHooksPath() string {
+	return "groups/" + gitlab.PathEscape(m.gitWebhook.Spec.RepositoryOwner) + "/hooks"
+}
+
+func (m *GitLabWebHook) deleteGroupHookIfExists(ctx context.Context) error {
+	log := log.FromContext(ctx)
+	hook, found, err := m.getGroupHook(ctx)
+	if err != nil {
+		log.Error(err, "unable to retrieve group webhook")
+		return err
+	}
+	if !found {
+		return nil
+	}
+	git, err := m.getClient(ctx)
+	if err != nil {
+		log.Error(err, "unable to create gitlab client")
+		return err
+	}
+	_, err = git.Groups.DeleteGroupHook(m.gitWebhook.Spec.RepositoryOwner, hook.ID, gitlab.WithContext(ctx))
+	if err != nil {
+		log.Error(err, "unable to delete group webhook")
+		return err
+	}
+	return nil
+}
+
+func (m *GitLabWebHook) reconcileGroupHook(ctx context.Context) error {
+	log := log.FromContext(ctx)
+	equivalent, actualHook, err := m.isGroupHookEquivalent(ctx)
+	if err != nil {
+		log.Error(err, "unable determine equivalency with actual state")
+		return err
+	}
+	if !equivalent {
+		actualHook, err = m.createOrUpdateGroupHook(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	m.recordDelivery(ctx, m.groupHooksPath()+"/"+strconv.Itoa(actualHook.ID), &actualHook.hookFields, true)
+	hook, err := m.toGroupHook(ctx)
+	if err != nil {
+		log.Error(err, "unable convert to gitlab group webhook")
+		return err
+	}
+	groupID, err := m.getGroupID(ctx)
+	if err != nil {
+		log.Error(err, "unable to retrieve group", "group", m.gitWebhook.Spec.RepositoryOwner)
+		return err
+	}
+	return m.recordAppliedWebHook(groupID, hook.EnableSSLVerification, hook.PushEventsBranchFilter)
+}
+
+// getGroupID returns the id of the group, the group of a GitWebhook cannot be changed so the id recorded in the status is reused
+func (m *GitLabWebHook) getGroupID(ctx context.Context) (string, error) {
+	if m.gitWebhook.Status.RepositoryID != "" {
+		return m.gitWebhook.Status.RepositoryID, nil
+	}
+	git, err := m.getClient(ctx)
+	if err != nil {
+		return "", err
+	}
+	group, _, err := git.Groups.GetGroup(m.gitWebhook.Spec.RepositoryOwner, &gitlab.GetGroupOptions{WithProjects: gitlab.Bool(false)}, gitlab.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	return strconv.Itoa(group.ID), nil
+}
+
+// createOrUpdateGroupHook returns the group hook created or updated
+func (m *GitLabWebHook) createOrUpdateGroupHook(ctx context.Context) (*groupHook, error) {
+	log := log.FromContext(ctx)
+	actualHook, found, err := m.getGroupHook(ctx)
+	if err != nil {
+		log.Error(err, "unable to retrieve group webhook")
+		return nil, err
+	}
+	git, err := m.getClient(ctx)
+	if err != nil {
+		log.Error(err, "unable to create gitlab client")
+		return nil, err
+	}
+	hook, err := m.toGroupHookOptions(ctx)
+	if err != nil {
+		log.Error(err, "unable to convert to group hook options")
+		return nil, err
+	}
+	method, path := http.MethodPost, m.groupHooksPath()
+	if found {
+		//we need to update it
+		method, path = http.MethodPut, m.groupHooksPath()+"/"+strconv.Itoa(actualHook.ID)
+	}
+	req, err := git.NewRequest(method, path, hook, []gitlab.RequestOptionFunc{gitlab.WithContext(ctx)})
+	if err != nil {
+		log.Error(err, "unable to create request")
+		return nil, err
+	}
+	newHook := &groupHook{}
+	_, err = git.Do(req, newHook)
+	if err != nil {
+		log.Error(err, "unable to create or update group webhook")
+		return nil, err
+	}
+	m.gitWebhook.SetOwnedHook(strconv.Itoa(newHook.ID))
+	return newHook, nil
+}
+
+// isGroupHookEquivalent compares the group hook with the GitWebhook, it also returns the hook found, nil when there is none
+func (m *GitLabWebHook) isGroupHookEquivalent(ctx context.Context) (bool, *groupHook, error) {
+	log := log.FromContext(ctx)
+	desiredHook, err := m.toGroupHook(ctx)
+	if err != nil {
+		log.Error(err, "unable convert to gitlab group webhook")
+		return false, nil, err
+	}
+	actualHook, found, err := m.getGroupHook(ctx)
+	if err != nil {
+		log.Error(err, "error while retrieving gitlab group webhook")
+		return false, nil, err
+	}
+	if !found {
+		return false, nil, nil
+	}
+	secret, err := m.gitWebhook.GetWebhookSecret(ctx)
+	if err != nil {
+		log.Error(err, "unable to retrieve webhook secret")
+		return false, nil, err
+	}
+	// the secret is not returned by the git server, a changed secret is detected with the fingerprint of the applied secret
+	if !m.gitWebhook.IsWebhookSecretApplied(secret) {
+		return false, actualHook, nil
+	}
+	// the read only fields are cleared on a copy, so that the hook found keeps its id and alert status
+	comparedHook := *actualHook
+	comparedHook.CreatedAt = nil
+	comparedHook.ID = 0
+	comparedHook.GroupID = 0
+	normalizeHookFields(&desiredHook.hookFields, &comparedHook.hookFields)
+	return reflect.DeepEqual(desiredHook, &comparedHook), actualHook, nil
+}
+
+func (m *GitLabWebHook) getGroupHook(ctx context.Context) (*groupHook, bool, error) {
+	log := log.FromContext(ctx)
+	git, err := m.getClient(ctx)
+	if err != nil {
+		log.Error(err, "unable to create gitlab client")
+		return nil, false, err
+	}
+	webhookURL, err := m.gitWebhook.GetWebhookURL(ctx)
+	if err != nil {
+		log.Error(err, "unable to retrieve webhook url")
+		return nil, false, err
+	}
+	finder := redhatcopv1alpha1.NewHookFinder[*groupHook](m.gitWebhook, &m.HookAdoption, webhookURL)
+	opt := &gitlab.ListOptions{
+		PerPage: 100,
+	}
+	for {
+		req, err := git.NewRequest(http.MethodGet, m.groupHooksPath(), opt, []gitlab.RequestOptionFunc{gitlab.WithContext(ctx)})
+		if err != nil {
+			log.Error(err, "unable to create request")
+			return nil, false, err
+		}
+		hooks := []*groupHook{}
+		response, err := git.Do(req, &hooks)
+		if err != nil {
+			log.Error(err, "unable to retrieve hooks for group", "group", m.gitWebhook.Spec.RepositoryOwner)
+			return nil, false, err
+		}
+		for _, hook := range hooks {
+			if finder.Owned(hook, strconv.Itoa(hook.ID), hook.URL) {
+				return hook, true, nil
+			}
+		}
+		if response.NextPage == 0 {
+			break
+		}
+		opt.Page = response.NextPage
+	}
+	return finder.Claim()
+}
+
+func (m *GitLabWebHook) toGroupHook(ctx context.Context) (*groupHook, error) {
+	webhookURL, err := m.gitWebhook.GetWebhookURL(ctx)
+	if err != nil {
+		return nil, err
+	}
+	groupHook := groupHook{
+		GroupHook: gitlab.GroupHook{
+			EnableSSLVerification:  !m.gitWebhook.Spec.InsecureSSL,
+			URL:                    webhookURL,
+			PushEventsBranchFilter: m.gitWebhook.Spec.PushEventBranchFilter,
+		},
+		hookFields: m.toHookFields(webhookURL),
+	}
+	err = m.addGitLabEvents(&groupHook)
+	if err != nil {
+		return nil, err
+	}
+	return &groupHook, nil
+}
+
+// toGroupHookOptions sets every event explicitly, so that events removed from the spec get disabled on update
+func (m *GitLabWebHook) toGroupHookOptions(ctx context.Context) (*groupHookOptions, error) {
+	log := log.FromContext(ctx)
+	secret, err := m.gitWebhook.GetWebhookSecret(ctx)
+	if err != nil {
+		log.Error(err, "unable to retrieve webhook secret")
+		return nil, err
+	}
+	hook, err := m.toGroupHook(ctx)
+	if err != nil {
+		return nil, err
+	}
+	hookOptionFields, err := m.toHookOptionFields(ctx, &hook.hookFields, hook.URL)
+	if err != nil {
+		return nil, err
+	}
+	groupHookOptions := groupHookOptions{
+		EditGroupHookOptions: gitlab.EditGroupHookOptions{
+			URL:                      &hook.URL,
+			PushEvents:               gitlab.Bool(hook.PushEvents),
+			PushEventsBranchFilter:   &hook.PushEventsBranchFilter,
+			IssuesEvents:             gitlab.Bool(hook.IssuesEvents),
+			ConfidentialIssuesEvents: gitlab.Bool(hook.ConfidentialIssuesEvents),
+			ConfidentialNoteEvents:   gitlab.Bool(hook.ConfidentialNoteEvents),
+			MergeRequestsEvents:      gitlab.Bool(hook.MergeRequestsEvents),
+			TagPushEvents:            gitlab.Bool(hook.TagPushEvents),
+			NoteEvents:               gitlab.Bool(hook.NoteEvents),
+			JobEvents:                gitlab.Bool(hook.JobEvents),
+			PipelineEvents:           gitlab.Bool(hook.PipelineEvents),
+			WikiPageEvents:           gitlab.Bool(hook.WikiPageEvents),
+			DeploymentEvents:         gitlab.Bool(hook.DeploymentEvents),
+			ReleasesEvents:           gitlab.Bool(hook.ReleasesEvents),
+			SubGroupEvents:           gitlab.Bool(hook.SubGroupEvents),
+			EnableSSLVerification:    gitlab.Bool(hook.EnableSSLVerification),
+			Token:                    &secret,
+		},
+		hookOptionFields: hookOptionFields,
+		MemberEvents:     gitlab.Bool(hook.MemberEvents),
+	}
+	return &groupHookOptions, nil
+}
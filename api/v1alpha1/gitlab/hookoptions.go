@@ -0,0 +1,137 @@
+package gitlab
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1"
+	"github.com/xanzy/go-gitlab"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// hookFields are the fields of the project and group hooks that the client library does not know about yet
+type hookFields struct {
+	Name                      string         `json:"name"`
+	Description               string         `json:"description"`
+	BranchFilterStrategy      string         `json:"branch_filter_strategy"`
+	CustomHeaders             []customHeader `json:"custom_headers"`
+	CustomWebhookTemplate     string         `json:"custom_webhook_template"`
+	EmojiEvents               bool           `json:"emoji_events"`
+	FeatureFlagEvents         bool           `json:"feature_flag_events"`
+	ResourceAccessTokenEvents bool           `json:"resource_access_token_events"`
+	URLVariables              []urlVariable  `json:"url_variables"`
+	// AlertStatus and DisabledUntil are read only, gitlab disables the hooks whose deliveries keep failing
+	AlertStatus   string     `json:"alert_status"`
+	DisabledUntil *time.Time `json:"disabled_until"`
+}
+
+// hookOptionFields are the options of the project and group hooks that the client library does not know about yet
+type hookOptionFields struct {
+	Name                      *string        `url:"name,omitempty" json:"name,omitempty"`
+	Description               *string        `url:"description,omitempty" json:"description,omitempty"`
+	BranchFilterStrategy      *string        `url:"branch_filter_strategy,omitempty" json:"branch_filter_strategy,omitempty"`
+	CustomHeaders             []customHeader `url:"-" json:"custom_headers"`
+	CustomWebhookTemplate     *string        `url:"custom_webhook_template,omitempty" json:"custom_webhook_template,omitempty"`
+	EmojiEvents               *bool          `url:"emoji_events,omitempty" json:"emoji_events,omitempty"`
+	FeatureFlagEvents         *bool          `url:"feature_flag_events,omitempty" json:"feature_flag_events,omitempty"`
+	ResourceAccessTokenEvents *bool          `url:"resource_access_token_events,omitempty" json:"resource_access_token_events,omitempty"`
+	URLVariables              []urlVariable  `url:"-" json:"url_variables,omitempty"`
+}
+
+// customHeader gitlab only returns the key of the custom headers, the value is write only
+type customHeader struct {
+	Key   string `json:"key"`
+	Value string `json:"value,omitempty"`
+}
+
+// urlVariable gitlab only returns the key of the url variables, the value is write only
+type urlVariable struct {
+	Key   string `json:"key"`
+	Value string `json:"value,omitempty"`
+}
+
+// toHookFields returns the hook fields described by the gitlab spec block, without the values of the custom headers and of the url variables
+func (m *GitLabWebHook) toHookFields(webhookURL string) hookFields {
+	config := m.gitWebhook.Spec.GitLab
+	fields := hookFields{
+		Name:                  config.HookName,
+		Description:           config.HookDescription,
+		BranchFilterStrategy:  config.BranchFilterStrategy,
+		CustomWebhookTemplate: config.CustomWebhookTemplate,
+	}
+	for _, header := range config.CustomHeaders {
+		fields.CustomHeaders = append(fields.CustomHeaders, customHeader{Key: header.Key})
+	}
+	sortCustomHeaders(fields.CustomHeaders)
+	for _, name := range redhatcopv1alpha1.URLVariableNames(webhookURL) {
+		fields.URLVariables = append(fields.URLVariables, urlVariable{Key: name})
+	}
+	sortURLVariables(fields.URLVariables)
+	return fields
+}
+
+// toHookOptionFields sets every option explicitly, so that options removed from the spec get reset on update
+func (m *GitLabWebHook) toHookOptionFields(ctx context.Context, fields *hookFields, webhookURL string) (hookOptionFields, error) {
+	log := log.FromContext(ctx)
+	urlVariables, err := m.gitWebhook.GetGitLabURLVariables(ctx, webhookURL)
+	if err != nil {
+		log.Error(err, "unable to retrieve url variables")
+		return hookOptionFields{}, err
+	}
+	options := hookOptionFields{
+		Name:                      &fields.Name,
+		Description:               &fields.Description,
+		CustomHeaders:             []customHeader{},
+		CustomWebhookTemplate:     &fields.CustomWebhookTemplate,
+		EmojiEvents:               gitlab.Bool(fields.EmojiEvents),
+		FeatureFlagEvents:         gitlab.Bool(fields.FeatureFlagEvents),
+		ResourceAccessTokenEvents: gitlab.Bool(fields.ResourceAccessTokenEvents),
+	}
+	if fields.BranchFilterStrategy != "" {
+		options.BranchFilterStrategy = &fields.BranchFilterStrategy
+	}
+	for _, header := range m.gitWebhook.Spec.GitLab.CustomHeaders {
+		options.CustomHeaders = append(options.CustomHeaders, customHeader{Key: header.Key, Value: header.Value})
+	}
+	for _, variable := range fields.URLVariables {
+		options.URLVariables = append(options.URLVariables, urlVariable{Key: variable.Key, Value: urlVariables[variable.Key]})
+	}
+	return options, nil
+}
+
+// normalizeHookFields clears from the actual hook what cannot be compared with the desired hook: the branch filter strategy defaulted by gitlab when it is not specified,
+// the ordering of the custom headers and of the url variables, and the read only fields
+func normalizeHookFields(desired *hookFields, actual *hookFields) {
+	actual.AlertStatus = ""
+	actual.DisabledUntil = nil
+	if desired.BranchFilterStrategy == "" {
+		actual.BranchFilterStrategy = ""
+	}
+	if len(actual.CustomHeaders) == 0 {
+		actual.CustomHeaders = nil
+	}
+	for i := range actual.CustomHeaders {
+		actual.CustomHeaders[i].Value = ""
+	}
+	sortCustomHeaders(actual.CustomHeaders)
+	if len(actual.URLVariables) == 0 {
+		actual.URLVariables = nil
+	}
+	for i := range actual.URLVariables {
+		actual.URLVariables[i].Value = ""
+	}
+	sortURLVariables(actual.URLVariables)
+}
+
+func sortCustomHeaders(headers []customHeader) {
+	sort.Slice(headers, func(i, j int) bool {
+		return headers[i].Key < headers[j].Key
+	})
+}
+
+func sortURLVariables(variables []urlVariable) {
+	sort.Slice(variables, func(i, j int) bool {
+		return variables[i].Key < variables[j].Key
+	})
+}
@@ -0,0 +1,107 @@
+package gitlab
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestNormalizeHookFields(t *testing.T) {
+	disabledUntil := time.Now()
+	tests := []struct {
+		name    string
+		desired hookFields
+		actual  hookFields
+		want    hookFields
+	}{
+		{
+			name:    "read only fields cleared",
+			desired: hookFields{Name: "ci"},
+			actual:  hookFields{Name: "ci", AlertStatus: "temporarily_disabled", DisabledUntil: &disabledUntil},
+			want:    hookFields{Name: "ci"},
+		},
+		{
+			name:    "defaulted branch filter strategy cleared",
+			desired: hookFields{},
+			actual:  hookFields{BranchFilterStrategy: "wildcard"},
+			want:    hookFields{},
+		},
+		{
+			name:    "branch filter strategy kept when specified",
+			desired: hookFields{BranchFilterStrategy: "regex"},
+			actual:  hookFields{BranchFilterStrategy: "wildcard"},
+			want:    hookFields{BranchFilterStrategy: "wildcard"},
+		},
+		{
+			name:    "empty lists cleared",
+			desired: hookFields{},
+			actual:  hookFields{CustomHeaders: []customHeader{}, URLVariables: []urlVariable{}},
+			want:    hookFields{},
+		},
+		{
+			name:    "custom headers sorted without their values",
+			desired: hookFields{},
+			actual:  hookFields{CustomHeaders: []customHeader{{Key: "X-Team", Value: "a"}, {Key: "Authorization", Value: "b"}}},
+			want:    hookFields{CustomHeaders: []customHeader{{Key: "Authorization"}, {Key: "X-Team"}}},
+		},
+		{
+			name:    "url variables sorted without their values",
+			desired: hookFields{},
+			actual:  hookFields{URLVariables: []urlVariable{{Key: "token", Value: "a"}, {Key: "path", Value: "b"}}},
+			want:    hookFields{URLVariables: []urlVariable{{Key: "path"}, {Key: "token"}}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			normalizeHookFields(&test.desired, &test.actual)
+			if !reflect.DeepEqual(test.actual, test.want) {
+				t.Errorf("normalizeHookFields() = %+v, want %+v", test.actual, test.want)
+			}
+		})
+	}
+}
+
+func TestToHookOptionFieldsURLVariables(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "url-variables", Namespace: "default"},
+		Data:       map[string][]byte{"receiver": []byte("flux"), "token": []byte("s3cr3t")},
+	}
+	ctx := context.WithValue(context.TODO(), "kubeClient", fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(secret).Build())
+	tests := []struct {
+		name       string
+		webhookURL string
+		want       []urlVariable
+		wantErr    bool
+	}{
+		{name: "no placeholder", webhookURL: "https://flux.example.com/hook"},
+		{
+			name:       "placeholders expanded from the secret",
+			webhookURL: "https://flux.example.com/{receiver}/{token}?token={token}",
+			want:       []urlVariable{{Key: "receiver", Value: "flux"}, {Key: "token", Value: "s3cr3t"}},
+		},
+		{name: "placeholder missing from the secret", webhookURL: "https://flux.example.com/hook/{missing}", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gitWebhook := &redhatcopv1alpha1.GitWebhook{}
+			gitWebhook.Namespace = "default"
+			gitWebhook.Spec.GitLab = &redhatcopv1alpha1.GitLabServerConfig{URLVariablesSecret: corev1.LocalObjectReference{Name: "url-variables"}}
+			m := &GitLabWebHook{gitWebhook: gitWebhook}
+			fields := m.toHookFields(test.webhookURL)
+			options, err := m.toHookOptionFields(ctx, &fields, test.webhookURL)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("toHookOptionFields() error = %v, want error %v", err, test.wantErr)
+			}
+			if !reflect.DeepEqual(options.URLVariables, test.want) {
+				t.Errorf("url variables = %+v, want %+v", options.URLVariables, test.want)
+			}
+		})
+	}
+}
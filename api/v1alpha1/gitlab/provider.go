@@ -0,0 +1,119 @@
+package gitlab
+
+import (
+	"errors"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1"
+)
+
+// events are the project and group hook events, subgroup_events and member_events are only supported by group hooks.
+// ReleasesEvents is kept for backward compatibility, releases_events is the name used by the gitlab api.
+var events = []string{
+	"confidential_issues_events",
+	"confidential_note_events",
+	"deployment_events",
+	"emoji_events",
+	"feature_flag_events",
+	"issues_events",
+	"job_events",
+	"member_events",
+	"merge_requests_events",
+	"note_events",
+	"pipeline_events",
+	"push_events",
+	"ReleasesEvents",
+	"releases_events",
+	"resource_access_token_events",
+	"subgroup_events",
+	"tag_push_events",
+	"wiki_page_events",
+}
+
+// groupHookEvents are the events that project hooks do not support
+var groupHookEvents = map[string]bool{
+	"member_events":   true,
+	"subgroup_events": true,
+}
+
+// hookEventFields are the fields of the project and group hooks that enable each event, the project hooks do not have the fields of the groupHookEvents
+var hookEventFields = map[string]string{
+	"confidential_issues_events":   "ConfidentialIssuesEvents",
+	"confidential_note_events":     "ConfidentialNoteEvents",
+	"deployment_events":            "DeploymentEvents",
+	"emoji_events":                 "EmojiEvents",
+	"feature_flag_events":          "FeatureFlagEvents",
+	"issues_events":                "IssuesEvents",
+	"job_events":                   "JobEvents",
+	"member_events":                "MemberEvents",
+	"merge_requests_events":        "MergeRequestsEvents",
+	"note_events":                  "NoteEvents",
+	"pipeline_events":              "PipelineEvents",
+	"push_events":                  "PushEvents",
+	"ReleasesEvents":               "ReleasesEvents",
+	"releases_events":              "ReleasesEvents",
+	"resource_access_token_events": "ResourceAccessTokenEvents",
+	"subgroup_events":              "SubGroupEvents",
+	"tag_push_events":              "TagPushEvents",
+	"wiki_page_events":             "WikiPageEvents",
+}
+
+func init() {
+	redhatcopv1alpha1.RegisterProvider(&provider{})
+}
+
+// provider registers gitlab with the GitWebhook controller
+type provider struct{}
+
+func (p *provider) Name() string {
+	return "gitLab"
+}
+
+func (p *provider) ServerConfig(spec *redhatcopv1alpha1.GitWebhookSpec) redhatcopv1alpha1.ServerConfig {
+	if spec.GitLab == nil {
+		return nil
+	}
+	return spec.GitLab
+}
+
+func (p *provider) NewWebHook(gitWebhook *redhatcopv1alpha1.GitWebhook) redhatcopv1alpha1.WebHook {
+	return FromGitWebhook(gitWebhook)
+}
+
+func (p *provider) CredentialKeys() [][]string {
+	return [][]string{{"token"}}
+}
+
+func (p *provider) Events() []string {
+	return events
+}
+
+func (p *provider) CanonicalEvents() map[string][]string {
+	return map[string][]string{
+		"push":         {"push_events"},
+		"tag":          {"tag_push_events"},
+		"pull_request": {"merge_requests_events"},
+		"issue":        {"issues_events"},
+		"comment":      {"note_events"},
+		"release":      {"releases_events"},
+		"pipeline":     {"pipeline_events"},
+		"deployment":   {"deployment_events"},
+		"wiki":         {"wiki_page_events"},
+	}
+}
+
+func (p *provider) SupportsOwnerWebHooks() bool {
+	return true
+}
+
+// ValidateSpec rejects the group hook events on project hooks, which are the hooks with a repositoryName
+func (p *provider) ValidateSpec(spec *redhatcopv1alpha1.GitWebhookSpec) error {
+	if spec.RepositoryName == "" {
+		return nil
+	}
+	for _, event := range spec.Events {
+		if groupHookEvents[event] {
+			return errors.New("event " + event + " is only supported by gitlab group hooks, which are managed when repositoryName is omitted")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package gitlab
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1"
+	"github.com/xanzy/go-gitlab"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// SystemHook manages an instance wide system hook, see https://docs.gitlab.com/ee/api/system_hooks.html
+type SystemHook struct {
+	gitLabSystemHook *redhatcopv1alpha1.GitLabSystemHook
+	gitlab           *gitlab.Client
+	redhatcopv1alpha1.HookAdoption
+}
+
+var _ redhatcopv1alpha1.WebHook = &SystemHook{}
+var _ redhatcopv1alpha1.HookAdopter = &SystemHook{}
+
+func FromGitLabSystemHook(gitLabSystemHook *redhatcopv1alpha1.GitLabSystemHook) *SystemHook {
+	return &SystemHook{
+		gitLabSystemHook: gitLabSystemHook,
+	}
+}
+
+func (m *SystemHook) Reconcile(ctx context.Context) error {
+	log := log.FromContext(ctx)
+	equivalent, err := m.isEquivalent(ctx)
+	if err != nil {
+		log.Error(err, "unable determine equivalency with actual state")
+		return err
+	}
+	if !equivalent {
+		return m.createOrReplace(ctx)
+	}
+	return nil
+}
+
+func (m *SystemHook) Delete(ctx context.Context) error {
+	return m.deleteIfExists(ctx)
+}
+
+func (m *SystemHook) deleteIfExists(ctx context.Context) error {
+	log := log.FromContext(ctx)
+	hook, found, err := m.getHook(ctx)
+	if err != nil {
+		log.Error(err, "unable to retrieve system hook")
+		return err
+	}
+	if !found {
+		return nil
+	}
+	git, err := m.getClient(ctx)
+	if err != nil {
+		log.Error(err, "unable to create gitlab client")
+		return err
+	}
+	_, err = git.SystemHooks.DeleteHook(hook.ID, gitlab.WithContext(ctx))
+	if err != nil {
+		log.Error(err, "unable to delete system hook")
+		return err
+	}
+	return nil
+}
+
+// createOrReplace creates the new hook before deleting the existing one, as system hooks cannot be edited, so that no delivery is lost and the existing hook is kept when the creation fails
+func (m *SystemHook) createOrReplace(ctx context.Context) error {
+	log := log.FromContext(ctx)
+	existing, found, err := m.getHook(ctx)
+	if err != nil {
+		log.Error(err, "unable to retrieve system hook")
+		return err
+	}
+	git, err := m.getClient(ctx)
+	if err != nil {
+		log.Error(err, "unable to create gitlab client")
+		return err
+	}
+	hook, err := m.toAddHookOptions(ctx)
+	if err != nil {
+		log.Error(err, "unable to convert to AddHookOptions")
+		return err
+	}
+	created, _, err := git.SystemHooks.AddHook(hook, gitlab.WithContext(ctx))
+	if err != nil {
+		log.Error(err, "unable to create system hook")
+		return err
+	}
+	m.gitLabSystemHook.SetOwnedHook(strconv.Itoa(created.ID))
+	m.gitLabSystemHook.SetAppliedWebhookSecret(*hook.Token)
+	if !found {
+		return nil
+	}
+	response, err := git.SystemHooks.DeleteHook(existing.ID, gitlab.WithContext(ctx))
+	if err != nil && (response == nil || response.StatusCode != http.StatusNotFound) {
+		log.Error(err, "unable to delete replaced system hook", "hookID", existing.ID)
+		return errors.New("unable to delete the replaced system hook " + strconv.Itoa(existing.ID) + ", it must be deleted by hand: " + err.Error())
+	}
+	return nil
+}
+
+func (m *SystemHook) isEquivalent(ctx context.Context) (bool, error) {
+	log := log.FromContext(ctx)
+	desiredHook, err := m.toHook()
+	if err != nil {
+		log.Error(err, "unable convert to gitlab system hook")
+		return false, err
+	}
+	actualHook, found, err := m.getHook(ctx)
+	if err != nil {
+		log.Error(err, "error while retrieving gitlab system hook")
+		return false, err
+	}
+	if !found {
+		return false, nil
+	}
+	// gitlab does not return the token, it is compared with the fingerprint of the token last applied
+	secret, err := m.gitLabSystemHook.GetWebhookSecret(ctx)
+	if err != nil {
+		log.Error(err, "unable to retrieve webhook secret")
+		return false, err
+	}
+	if !m.gitLabSystemHook.IsWebhookSecretApplied(secret) {
+		return false, nil
+	}
+	actualHook.CreatedAt = nil
+	actualHook.ID = 0
+	return reflect.DeepEqual(desiredHook, actualHook), nil
+}
+
+// getHook returns the system hook created or adopted by the GitLabSystemHook, or else the first system hook with its url when the adoption policy allows to adopt it
+func (m *SystemHook) getHook(ctx context.Context) (*gitlab.Hook, bool, error) {
+	log := log.FromContext(ctx)
+	git, err := m.getClient(ctx)
+	if err != nil {
+		log.Error(err, "unable to create gitlab client")
+		return nil, false, err
+	}
+	finder := redhatcopv1alpha1.NewHookFinder[*gitlab.Hook](m.gitLabSystemHook, &m.HookAdoption, m.gitLabSystemHook.Spec.WebhookURL)
+	// the client library does not paginate the system hooks, the request is built to walk the pages
+	opt := &gitlab.ListOptions{
+		PerPage: 100,
+	}
+	for {
+		req, err := git.NewRequest(http.MethodGet, "hooks", opt, []gitlab.RequestOptionFunc{gitlab.WithContext(ctx)})
+		if err != nil {
+			log.Error(err, "unable to create request")
+			return nil, false, err
+		}
+		hooks := []*gitlab.Hook{}
+		response, err := git.Do(req, &hooks)
+		if err != nil {
+			log.Error(err, "unable to retrieve system hooks")
+			return nil, false, err
+		}
+		for _, hook := range hooks {
+			if finder.Owned(hook, strconv.Itoa(hook.ID), hook.URL) {
+				return hook, true, nil
+			}
+		}
+		if response.NextPage == 0 {
+			break
+		}
+		opt.Page = response.NextPage
+	}
+	return finder.Claim()
+}
+
+func (m *SystemHook) toHook() (*gitlab.Hook, error) {
+	hook := gitlab.Hook{
+		URL:                   m.gitLabSystemHook.Spec.WebhookURL,
+		EnableSSLVerification: !m.gitLabSystemHook.Spec.InsecureSSL,
+	}
+	for _, event := range m.gitLabSystemHook.Spec.Events {
+		switch event {
+		case "merge_requests_events":
+			hook.MergeRequestsEvents = true
+		case "push_events":
+			hook.PushEvents = true
+		case "repository_update_events":
+			hook.RepositoryUpdateEvents = true
+		case "tag_push_events":
+			hook.TagPushEvents = true
+		default:
+			return nil, errors.New("unknown event type:" + event)
+		}
+	}
+	return &hook, nil
+}
+
+// toAddHookOptions sets every event explicitly, as gitlab enables push events when they are not specified
+func (m *SystemHook) toAddHookOptions(ctx context.Context) (*gitlab.AddHookOptions, error) {
+	log := log.FromContext(ctx)
+	secret, err := m.gitLabSystemHook.GetWebhookSecret(ctx)
+	if err != nil {
+		log.Error(err, "unable to retrieve webhook secret")
+		return nil, err
+	}
+	hook, err := m.toHook()
+	if err != nil {
+		return nil, err
+	}
+	return &gitlab.AddHookOptions{
+		URL:                    &hook.URL,
+		Token:                  &secret,
+		PushEvents:             gitlab.Bool(hook.PushEvents),
+		TagPushEvents:          gitlab.Bool(hook.TagPushEvents),
+		MergeRequestsEvents:    gitlab.Bool(hook.MergeRequestsEvents),
+		RepositoryUpdateEvents: gitlab.Bool(hook.RepositoryUpdateEvents),
+		EnableSSLVerification:  gitlab.Bool(hook.EnableSSLVerification),
+	}, nil
+}
+
+func (m *SystemHook) getClient(ctx context.Context) (*gitlab.Client, error) {
+	if m.gitlab != nil {
+		return m.gitlab, nil
+	}
+	log := log.FromContext(ctx)
+	token, err := m.gitLabSystemHook.GetGitCredential(ctx)
+	if err != nil {
+		log.Error(err, "Unable to retrieve gitlab credential", "secret", m.gitLabSystemHook.Spec.GitLab.GitServerCredentials.Namespace+"/"+m.gitLabSystemHook.Spec.GitLab.GitServerCredentials.Name)
+		return nil, err
+	}
+	git, err := newClient(token, m.gitLabSystemHook.Spec.GitLab.GitLabAPIServerURL)
+	if err != nil {
+		log.Error(err, "Failed to create gitlab client", "url", m.gitLabSystemHook.Spec.GitLab.GitLabAPIServerURL)
+		return nil, err
+	}
+	m.gitlab = git
+	return git, nil
+}
@@ -0,0 +1,199 @@
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1"
+	"github.com/redhat-cop/gitwebhook-operator/api/v1alpha1/webhooktest"
+	"github.com/xanzy/go-gitlab"
+)
+
+// fakeSystemHooksServer serves the system hooks of a gitlab instance, one hook per page so that the listing is paginated
+type fakeSystemHooksServer struct {
+	*webhooktest.Server
+	hooks      []*gitlab.Hook
+	lastID     int
+	failCreate bool
+}
+
+func newFakeSystemHooksServer(t *testing.T, hooks ...*gitlab.Hook) *fakeSystemHooksServer {
+	s := &fakeSystemHooksServer{hooks: hooks, lastID: 100}
+	s.Server = webhooktest.NewServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		id := strings.TrimPrefix(r.URL.Path, "/api/v4/hooks/")
+		switch {
+		case r.URL.Path == "/api/v4/":
+			// the client reads the rate limit of the server when it is created
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v4/hooks":
+			page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+			if page == 0 {
+				page = 1
+			}
+			hooks := []*gitlab.Hook{}
+			if page <= len(s.hooks) {
+				hooks = append(hooks, s.hooks[page-1])
+			}
+			if page < len(s.hooks) {
+				w.Header().Set("X-Next-Page", strconv.Itoa(page+1))
+			}
+			json.NewEncoder(w).Encode(hooks)
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v4/hooks":
+			if s.failCreate {
+				// a client error, so that the client does not retry
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				return
+			}
+			options := &gitlab.AddHookOptions{}
+			json.NewDecoder(r.Body).Decode(options)
+			s.lastID++
+			created := &gitlab.Hook{
+				ID:                     s.lastID,
+				URL:                    *options.URL,
+				PushEvents:             *options.PushEvents,
+				TagPushEvents:          *options.TagPushEvents,
+				MergeRequestsEvents:    *options.MergeRequestsEvents,
+				RepositoryUpdateEvents: *options.RepositoryUpdateEvents,
+				EnableSSLVerification:  *options.EnableSSLVerification,
+			}
+			s.hooks = append(s.hooks, created)
+			json.NewEncoder(w).Encode(created)
+		case r.Method == http.MethodDelete && id != r.URL.Path:
+			for i, existing := range s.hooks {
+				if strconv.Itoa(existing.ID) == id {
+					s.hooks = append(s.hooks[:i], s.hooks[i+1:]...)
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+			}
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			webhooktest.UnexpectedRequest(t, w, r)
+		}
+	})
+	return s
+}
+
+func newTestSystemHook(t *testing.T, server *fakeSystemHooksServer, gitLabSystemHook *redhatcopv1alpha1.GitLabSystemHook) *SystemHook {
+	git, err := gitlab.NewClient("token", gitlab.WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &SystemHook{gitLabSystemHook: gitLabSystemHook, gitlab: git}
+}
+
+func newTestGitLabSystemHook(adoptionPolicy string) *redhatcopv1alpha1.GitLabSystemHook {
+	gitLabSystemHook := &redhatcopv1alpha1.GitLabSystemHook{}
+	gitLabSystemHook.Name = "system-hook"
+	gitLabSystemHook.Spec = redhatcopv1alpha1.GitLabSystemHookSpec{
+		WebhookURL:     "https://hooks.example.com/system",
+		Events:         []string{"push_events"},
+		AdoptionPolicy: adoptionPolicy,
+	}
+	return gitLabSystemHook
+}
+
+func TestSystemHookLifecycle(t *testing.T) {
+	server := newFakeSystemHooksServer(t,
+		&gitlab.Hook{ID: 1, URL: "https://other.example.com/system", PushEvents: true, EnableSSLVerification: true},
+		&gitlab.Hook{ID: 2, URL: "https://other.example.com/system", PushEvents: true, EnableSSLVerification: true},
+	)
+	gitLabSystemHook := newTestGitLabSystemHook("Adopt")
+
+	webhooktest.RunLifecycle(t, server.Server, func() redhatcopv1alpha1.WebHook { return newTestSystemHook(t, server, gitLabSystemHook) },
+		// the hooks of the other receivers are listed over several pages
+		webhooktest.Step{
+			Name:      "create",
+			Mutations: []string{"POST /api/v4/hooks"},
+			Check: func(t *testing.T, webHook redhatcopv1alpha1.WebHook) {
+				if adopted := webHook.(*SystemHook).AdoptedHookID(); gitLabSystemHook.Status.HookID != "101" || adopted != "" {
+					t.Fatalf("create: hookID = %q, adopted %q, want 101 created", gitLabSystemHook.Status.HookID, adopted)
+				}
+			},
+		},
+		// the owned hook is found on the last page
+		webhooktest.Step{Name: "resync"},
+		// the new hook is created before the replaced one is deleted
+		webhooktest.Step{
+			Name:      "replace",
+			Change:    func() { gitLabSystemHook.Spec.Events = []string{"push_events", "tag_push_events"} },
+			Mutations: []string{"POST /api/v4/hooks", "DELETE /api/v4/hooks/101"},
+			Check: func(t *testing.T, _ redhatcopv1alpha1.WebHook) {
+				if gitLabSystemHook.Status.HookID != "102" {
+					t.Fatalf("replace: hookID = %q, want 102", gitLabSystemHook.Status.HookID)
+				}
+			},
+		},
+		// a failed creation keeps the existing hook
+		webhooktest.Step{
+			Name: "failed replace",
+			Change: func() {
+				gitLabSystemHook.Spec.Events = []string{"push_events"}
+				server.failCreate = true
+			},
+			Error:     true,
+			Mutations: []string{"POST /api/v4/hooks"},
+			Check: func(t *testing.T, _ redhatcopv1alpha1.WebHook) {
+				if gitLabSystemHook.Status.HookID != "102" || len(server.hooks) != 3 {
+					t.Fatalf("failed replace: hookID = %q and hooks %+v, want 102 kept", gitLabSystemHook.Status.HookID, server.hooks)
+				}
+			},
+		},
+		// the hooks of the other receivers are left alone
+		webhooktest.Step{
+			Name:      "delete",
+			Change:    func() { server.failCreate = false },
+			Delete:    true,
+			Mutations: []string{"DELETE /api/v4/hooks/102"},
+			Check: func(t *testing.T, _ redhatcopv1alpha1.WebHook) {
+				if len(server.hooks) != 2 || server.hooks[0].ID != 1 || server.hooks[1].ID != 2 {
+					t.Fatalf("delete: unexpected remaining hooks %+v", server.hooks)
+				}
+			},
+		},
+	)
+}
+
+func TestSystemHookAdoption(t *testing.T) {
+	tests := []struct {
+		name          string
+		policy        string
+		wantConflict  bool
+		wantHookID    string
+		wantAdopted   string
+		wantMutations []string
+	}{
+		{name: "adopt", policy: "Adopt", wantHookID: "1", wantAdopted: "1"},
+		{name: "fail if exists", policy: "FailIfExists", wantConflict: true},
+		{name: "ignore", policy: "Ignore", wantHookID: "101", wantMutations: []string{"POST /api/v4/hooks"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := newFakeSystemHooksServer(t,
+				&gitlab.Hook{ID: 1, URL: "https://hooks.example.com/system", PushEvents: true, EnableSSLVerification: true},
+			)
+			gitLabSystemHook := newTestGitLabSystemHook(test.policy)
+			// the hook has no secret, so it is in sync once adopted
+			gitLabSystemHook.SetAppliedWebhookSecret("")
+
+			webHook := newTestSystemHook(t, server, gitLabSystemHook)
+			err := webHook.Reconcile(context.TODO())
+			var conflict *redhatcopv1alpha1.HookConflictError
+			if errors.As(err, &conflict) != test.wantConflict || (err != nil && !test.wantConflict) {
+				t.Fatalf("Reconcile() error = %v, want conflict %v", err, test.wantConflict)
+			}
+			if mutations := server.Mutations(); !reflect.DeepEqual(mutations, append([]string{}, test.wantMutations...)) {
+				t.Fatalf("requests = %v, want %v", mutations, test.wantMutations)
+			}
+			if gitLabSystemHook.Status.HookID != test.wantHookID || webHook.AdoptedHookID() != test.wantAdopted {
+				t.Errorf("hookID = %q, adopted %q, want %q, adopted %q", gitLabSystemHook.Status.HookID, webHook.AdoptedHookID(), test.wantHookID, test.wantAdopted)
+			}
+		})
+	}
+}
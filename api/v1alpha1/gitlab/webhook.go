@@ -3,7 +3,9 @@ package gitlab
 import (
 	"context"
 	"errors"
+	"net/http"
 	"reflect"
+	"strconv"
 
 	redhatcopv1alpha1 "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1"
 	"github.com/xanzy/go-gitlab"
@@ -14,9 +16,26 @@ type GitLabWebHook struct {
 	gitWebhook *redhatcopv1alpha1.GitWebhook
 	project    *gitlab.Project
 	gitlab     *gitlab.Client
+	applied    *redhatcopv1alpha1.AppliedWebHook
+	redhatcopv1alpha1.HookAdoption
+}
+
+// projectHook adds to gitlab.ProjectHook the fields that the client library does not know about yet
+type projectHook struct {
+	gitlab.ProjectHook
+	hookFields
+}
+
+// projectHookOptions adds to gitlab.EditProjectHookOptions the fields that the client library does not know about yet, add and edit accept the same options
+type projectHookOptions struct {
+	gitlab.EditProjectHookOptions
+	hookOptionFields
 }
 
 var _ redhatcopv1alpha1.WebHook = &GitLabWebHook{}
+var _ redhatcopv1alpha1.DriftDetector = &GitLabWebHook{}
+var _ redhatcopv1alpha1.AppliedWebHookReporter = &GitLabWebHook{}
+var _ redhatcopv1alpha1.HookAdopter = &GitLabWebHook{}
 
 func FromGitWebhook(gitwebhook *redhatcopv1alpha1.GitWebhook) *GitLabWebHook {
 	return &GitLabWebHook{
@@ -25,13 +44,39 @@ func FromGitWebhook(gitwebhook *redhatcopv1alpha1.GitWebhook) *GitLabWebHook {
 }
 
 func (m *GitLabWebHook) Reconcile(ctx context.Context) error {
+	if m.isGroupHook() {
+		return m.reconcileGroupHook(ctx)
+	}
 	return m.reconcile(ctx)
 }
 
 func (m *GitLabWebHook) Delete(ctx context.Context) error {
+	if m.isGroupHook() {
+		return m.deleteGroupHookIfExists(ctx)
+	}
 	return m.deleteIfExists(ctx)
 }
 
+// IsEquivalent records the alert status of the hook found, the last event of the hook is only read when it is reconciled, so that the periodic resyncs cost no extra request
+func (m *GitLabWebHook) IsEquivalent(ctx context.Context) (bool, error) {
+	if m.isGroupHook() {
+		equivalent, hook, err := m.isGroupHookEquivalent(ctx)
+		if hook != nil {
+			m.recordDelivery(ctx, m.groupHooksPath()+"/"+strconv.Itoa(hook.ID), &hook.hookFields, false)
+		}
+		return equivalent, err
+	}
+	equivalent, hook, err := m.isEquivalent(ctx)
+	if hook != nil {
+		m.recordDelivery(ctx, projectHooksPath(m.project)+"/"+strconv.Itoa(hook.ID), &hook.hookFields, false)
+	}
+	return equivalent, err
+}
+
+func (m *GitLabWebHook) AppliedWebHook() *redhatcopv1alpha1.AppliedWebHook {
+	return m.applied
+}
+
 func (m *GitLabWebHook) deleteIfExists(ctx context.Context) error {
 	log := log.FromContext(ctx)
 	project, found, err := m.getProject(ctx)
@@ -65,126 +110,165 @@ func (m *GitLabWebHook) deleteIfExists(ctx context.Context) error {
 
 func (m *GitLabWebHook) reconcile(ctx context.Context) error {
 	log := log.FromContext(ctx)
-	equivalent, err := m.isEquivalent(ctx)
+	equivalent, actualHook, err := m.isEquivalent(ctx)
 	if err != nil {
 		log.Error(err, "unable determine equivalency with actual state")
 		return err
 	}
 	if !equivalent {
-		return m.createOrUpdate(ctx)
+		actualHook, err = m.createOrUpdate(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	m.recordDelivery(ctx, projectHooksPath(m.project)+"/"+strconv.Itoa(actualHook.ID), &actualHook.hookFields, true)
+	hook, err := m.toProjectHook(ctx)
+	if err != nil {
+		log.Error(err, "unable convert to gitlab webhook")
+		return err
+	}
+	return m.recordAppliedWebHook(strconv.Itoa(m.project.ID), hook.EnableSSLVerification, hook.PushEventsBranchFilter)
+}
+
+// recordAppliedWebHook records the project or group hook sent to or found in sync on gitlab, gitlab hooks have no content type and cannot be deactivated
+func (m *GitLabWebHook) recordAppliedWebHook(repositoryID string, enableSSLVerification bool, pushEventsBranchFilter string) error {
+	events, err := m.gitWebhook.GetNativeEvents()
+	if err != nil {
+		return err
+	}
+	insecureSSL := !enableSSLVerification
+	m.applied = &redhatcopv1alpha1.AppliedWebHook{
+		RepositoryID: repositoryID,
+		Events:       events,
+		Settings: redhatcopv1alpha1.AppliedWebhookSettings{
+			InsecureSSL:           &insecureSSL,
+			PushEventBranchFilter: pushEventsBranchFilter,
+		},
 	}
 	return nil
 }
 
-func (m *GitLabWebHook) createOrUpdate(ctx context.Context) error {
+// createOrUpdate returns the hook created or updated
+func (m *GitLabWebHook) createOrUpdate(ctx context.Context) (*projectHook, error) {
 	log := log.FromContext(ctx)
 	project, found, err := m.getProject(ctx)
 	if err != nil {
 		log.Error(err, "unable to get gitlab project")
-		return err
+		return nil, err
 	}
 	if !found {
-		return errors.New("unable to find project")
+		return nil, errors.New("unable to find project")
 	}
 	actualHook, found, err := m.getHook(ctx)
 	if err != nil {
 		log.Error(err, "unable to retrieve webhook")
-		return err
+		return nil, err
 	}
 	git, err := m.getClient(ctx)
 	if err != nil {
 		log.Error(err, "unable to create gitlab client")
-		return err
+		return nil, err
 	}
-	if !found {
-		//we need to create it
-		hook, err := m.toAddProjectHookOptions(ctx)
-		if err != nil {
-			log.Error(err, "unable to convert to ProjectHookOptions")
-			return err
-		}
-		_, _, err = git.Projects.AddProjectHook(project.ID, hook, gitlab.WithContext(ctx))
-		if err != nil {
-			log.Error(err, "unable to create webhook")
-			return err
-		}
-	} else {
+	hook, err := m.toProjectHookOptions(ctx)
+	if err != nil {
+		log.Error(err, "unable to convert to ProjectHookOptions")
+		return nil, err
+	}
+	method, path := http.MethodPost, projectHooksPath(project)
+	if found {
 		//we need to update it
-		hook, err := m.toEditProjectHookOptions(ctx)
-		if err != nil {
-			log.Error(err, "unable to convert to ProjectHookOptions")
-			return err
-		}
-		_, _, err = git.Projects.EditProjectHook(project.ID, actualHook.ID, hook, gitlab.WithContext(ctx))
-		if err != nil {
-			log.Error(err, "unable to update webhook")
-			return err
-		}
+		method, path = http.MethodPut, projectHooksPath(project)+"/"+strconv.Itoa(actualHook.ID)
 	}
-	return nil
+	req, err := git.NewRequest(method, path, hook, []gitlab.RequestOptionFunc{gitlab.WithContext(ctx)})
+	if err != nil {
+		log.Error(err, "unable to create request")
+		return nil, err
+	}
+	newHook := &projectHook{}
+	_, err = git.Do(req, newHook)
+	if err != nil {
+		log.Error(err, "unable to create or update webhook")
+		return nil, err
+	}
+	m.gitWebhook.SetOwnedHook(strconv.Itoa(newHook.ID))
+	return newHook, nil
 }
 
-func (m *GitLabWebHook) isEquivalent(ctx context.Context) (bool, error) {
+// isEquivalent compares the project hook with the GitWebhook, it also returns the hook found, nil when there is none
+func (m *GitLabWebHook) isEquivalent(ctx context.Context) (bool, *projectHook, error) {
 	log := log.FromContext(ctx)
 	desiredHook, err := m.toProjectHook(ctx)
 	if err != nil {
 		log.Error(err, "unable convert to gitlab webhook")
-		return false, err
+		return false, nil, err
 	}
 	actualHook, found, err := m.getHook(ctx)
 	if err != nil {
 		log.Error(err, "error while retrieving gitlab webhook")
-		return false, err
+		return false, nil, err
 	}
 	if !found {
-		return false, nil
+		return false, nil, nil
 	}
-	actualHook.CreatedAt = nil
-	actualHook.ID = 0
-	actualHook.ProjectID = 0
-	return reflect.DeepEqual(desiredHook, actualHook), nil
+	secret, err := m.gitWebhook.GetWebhookSecret(ctx)
+	if err != nil {
+		log.Error(err, "unable to retrieve webhook secret")
+		return false, nil, err
+	}
+	// the secret is not returned by the git server, a changed secret is detected with the fingerprint of the applied secret
+	if !m.gitWebhook.IsWebhookSecretApplied(secret) {
+		return false, actualHook, nil
+	}
+	// the read only fields are cleared on a copy, so that the hook found keeps its id and alert status
+	comparedHook := *actualHook
+	comparedHook.CreatedAt = nil
+	comparedHook.ID = 0
+	comparedHook.ProjectID = 0
+	normalizeHookFields(&desiredHook.hookFields, &comparedHook.hookFields)
+	return reflect.DeepEqual(desiredHook, &comparedHook), actualHook, nil
 }
 
+// getProject resolves the project by its full path, RepositoryOwner being the full path of the namespace, including nested subgroups.
+// A numeric RepositoryName is used as the project id.
 func (m *GitLabWebHook) getProject(ctx context.Context) (*gitlab.Project, bool, error) {
 	if m.project != nil {
 		return m.project, true, nil
 	}
 	log := log.FromContext(ctx)
-	if m.project != nil {
-		return m.project, true, nil
-	}
 	git, err := m.getClient(ctx)
 	if err != nil {
 		log.Error(err, "unable to create gitlab client")
 		return nil, false, err
 	}
-	projects, _, err := git.Projects.ListUserProjects(m.gitWebhook.Spec.RepositoryOwner, &gitlab.ListProjectsOptions{}, gitlab.WithContext(ctx))
-	if err != nil {
-		log.Error(err, "unable to list gitlab projects", "for owner", m.gitWebhook.Spec.RepositoryOwner)
-		return nil, false, err
-	}
-	for _, project := range projects {
-		if project.Name == m.gitWebhook.Spec.RepositoryName {
-			m.project = project
-			return project, true, nil
+	// the path is tried first, as a project can be named with digits, the name is read as a project id when no project has that path
+	pids := []interface{}{m.gitWebhook.Spec.RepositoryOwner + "/" + m.gitWebhook.Spec.RepositoryName}
+	if id, err := strconv.Atoi(m.gitWebhook.Spec.RepositoryName); err == nil {
+		pids = append(pids, id)
+	}
+	var project *gitlab.Project
+	for _, pid := range pids {
+		var response *gitlab.Response
+		project, response, err = git.Projects.GetProject(pid, &gitlab.GetProjectOptions{}, gitlab.WithContext(ctx))
+		if err == nil {
+			break
 		}
-	}
-	// if we get here we need to try the group projects
-	projects, _, err = git.Groups.ListGroupProjects(m.gitWebhook.Spec.RepositoryOwner, &gitlab.ListGroupProjectsOptions{}, gitlab.WithContext(ctx))
-	if err != nil {
-		log.Error(err, "unable to list gitlab projects", "for owner", m.gitWebhook.Spec.RepositoryOwner)
-		return nil, false, err
-	}
-	for _, project := range projects {
-		if project.Name == m.gitWebhook.Spec.RepositoryName {
-			m.project = project
-			return project, true, nil
+		if response == nil || response.StatusCode != http.StatusNotFound {
+			log.Error(err, "unable to retrieve gitlab project", "project", pid)
+			return nil, false, err
 		}
 	}
-	return nil, false, nil
+	if project == nil {
+		return nil, false, nil
+	}
+	m.project = project
+	return project, true, nil
+}
+
+func projectHooksPath(project *gitlab.Project) string {
+	return "projects/" + strconv.Itoa(project.ID) + "/hooks"
 }
 
-func (m *GitLabWebHook) getHook(ctx context.Context) (*gitlab.ProjectHook, bool, error) {
+func (m *GitLabWebHook) getHook(ctx context.Context) (*projectHook, bool, error) {
 	log := log.FromContext(ctx)
 	git, err := m.getClient(ctx)
 	if err != nil {
@@ -199,173 +283,116 @@ func (m *GitLabWebHook) getHook(ctx context.Context) (*gitlab.ProjectHook, bool,
 	if !found {
 		return nil, false, nil
 	}
-	hooks, _, err := git.Projects.ListProjectHooks(project.ID, &gitlab.ListProjectHooksOptions{}, gitlab.WithContext(ctx))
+	webhookURL, err := m.gitWebhook.GetWebhookURL(ctx)
 	if err != nil {
-		log.Error(err, "unable to retrieve hooks for project")
+		log.Error(err, "unable to retrieve webhook url")
 		return nil, false, err
 	}
-	for _, hook := range hooks {
-		if hook.URL == m.gitWebhook.Spec.WebhookURL {
-			return hook, true, nil
+	finder := redhatcopv1alpha1.NewHookFinder[*projectHook](m.gitWebhook, &m.HookAdoption, webhookURL)
+	opt := &gitlab.ListOptions{
+		PerPage: 100,
+	}
+	for {
+		req, err := git.NewRequest(http.MethodGet, projectHooksPath(project), opt, []gitlab.RequestOptionFunc{gitlab.WithContext(ctx)})
+		if err != nil {
+			log.Error(err, "unable to create request")
+			return nil, false, err
+		}
+		hooks := []*projectHook{}
+		response, err := git.Do(req, &hooks)
+		if err != nil {
+			log.Error(err, "unable to retrieve hooks for project")
+			return nil, false, err
 		}
+		for _, hook := range hooks {
+			if finder.Owned(hook, strconv.Itoa(hook.ID), hook.URL) {
+				return hook, true, nil
+			}
+		}
+		if response.NextPage == 0 {
+			break
+		}
+		opt.Page = response.NextPage
 	}
-	return nil, false, nil
+	return finder.Claim()
 }
 
-func (m *GitLabWebHook) toEditProjectHookOptions(ctx context.Context) (*gitlab.EditProjectHookOptions, error) {
-	log := log.FromContext(ctx)
-	secret, err := m.gitWebhook.GetWebhookSecret(ctx)
+func (m *GitLabWebHook) toProjectHook(ctx context.Context) (*projectHook, error) {
+	webhookURL, err := m.gitWebhook.GetWebhookURL(ctx)
 	if err != nil {
-		log.Error(err, "unable to retrieve webhook secret")
 		return nil, err
 	}
-	editProjectHookOptions := gitlab.EditProjectHookOptions{
-		EnableSSLVerification:  gitlab.Bool(!m.gitWebhook.Spec.InsecureSSL),
-		Token:                  &secret,
-		URL:                    &m.gitWebhook.Spec.WebhookURL,
-		PushEventsBranchFilter: &m.gitWebhook.Spec.PushEventBranchFilter,
+	projectHook := projectHook{
+		ProjectHook: gitlab.ProjectHook{
+			EnableSSLVerification:  !m.gitWebhook.Spec.InsecureSSL,
+			URL:                    webhookURL,
+			PushEventsBranchFilter: m.gitWebhook.Spec.PushEventBranchFilter,
+		},
+		hookFields: m.toHookFields(webhookURL),
 	}
-	err = m.addGitLabEventsToEditProjectHookOptions(&editProjectHookOptions)
-	if err != nil {
-		return nil, err
-	}
-	return &editProjectHookOptions, nil
-}
-
-func (m *GitLabWebHook) toProjectHook(ctx context.Context) (*gitlab.ProjectHook, error) {
-	projectHook := gitlab.ProjectHook{
-		EnableSSLVerification:  !m.gitWebhook.Spec.InsecureSSL,
-		URL:                    m.gitWebhook.Spec.WebhookURL,
-		PushEventsBranchFilter: m.gitWebhook.Spec.PushEventBranchFilter,
-	}
-	err := m.addGitLabEventsToProjectHook(&projectHook)
+	err = m.addGitLabEvents(&projectHook)
 	if err != nil {
 		return nil, err
 	}
 	return &projectHook, nil
 }
 
-func (m *GitLabWebHook) toAddProjectHookOptions(ctx context.Context) (*gitlab.AddProjectHookOptions, error) {
+// toProjectHookOptions sets every event explicitly, so that events removed from the spec get disabled on update
+func (m *GitLabWebHook) toProjectHookOptions(ctx context.Context) (*projectHookOptions, error) {
 	log := log.FromContext(ctx)
 	secret, err := m.gitWebhook.GetWebhookSecret(ctx)
 	if err != nil {
 		log.Error(err, "unable to retrieve webhook secret")
 		return nil, err
 	}
-	addProjectOptions := gitlab.AddProjectHookOptions{
-		EnableSSLVerification:  gitlab.Bool(!m.gitWebhook.Spec.InsecureSSL),
-		Token:                  &secret,
-		URL:                    &m.gitWebhook.Spec.WebhookURL,
-		PushEventsBranchFilter: &m.gitWebhook.Spec.PushEventBranchFilter,
+	hook, err := m.toProjectHook(ctx)
+	if err != nil {
+		return nil, err
 	}
-	err = m.addGitLabEventsToAddProjectHookOptions(&addProjectOptions)
+	hookOptionFields, err := m.toHookOptionFields(ctx, &hook.hookFields, hook.URL)
 	if err != nil {
 		return nil, err
 	}
-	return &addProjectOptions, nil
+	projectHookOptions := projectHookOptions{
+		EditProjectHookOptions: gitlab.EditProjectHookOptions{
+			URL:                      &hook.URL,
+			PushEvents:               gitlab.Bool(hook.PushEvents),
+			PushEventsBranchFilter:   &hook.PushEventsBranchFilter,
+			IssuesEvents:             gitlab.Bool(hook.IssuesEvents),
+			ConfidentialIssuesEvents: gitlab.Bool(hook.ConfidentialIssuesEvents),
+			ConfidentialNoteEvents:   gitlab.Bool(hook.ConfidentialNoteEvents),
+			MergeRequestsEvents:      gitlab.Bool(hook.MergeRequestsEvents),
+			TagPushEvents:            gitlab.Bool(hook.TagPushEvents),
+			NoteEvents:               gitlab.Bool(hook.NoteEvents),
+			JobEvents:                gitlab.Bool(hook.JobEvents),
+			PipelineEvents:           gitlab.Bool(hook.PipelineEvents),
+			WikiPageEvents:           gitlab.Bool(hook.WikiPageEvents),
+			DeploymentEvents:         gitlab.Bool(hook.DeploymentEvents),
+			ReleasesEvents:           gitlab.Bool(hook.ReleasesEvents),
+			EnableSSLVerification:    gitlab.Bool(hook.EnableSSLVerification),
+			Token:                    &secret,
+		},
+		hookOptionFields: hookOptionFields,
+	}
+	return &projectHookOptions, nil
 }
 
-func (m *GitLabWebHook) addGitLabEventsToProjectHook(projectHook *gitlab.ProjectHook) error {
-	True := true
-	for _, event := range m.gitWebhook.Spec.Events {
-		switch event {
-		case "confidential_issues_events":
-			projectHook.ConfidentialIssuesEvents = True
-		case "confidential_note_events":
-			projectHook.ConfidentialNoteEvents = True
-		case "deployment_events":
-			projectHook.DeploymentEvents = True
-		case "issues_events":
-			projectHook.IssuesEvents = True
-		case "job_events":
-			projectHook.JobEvents = True
-		case "merge_requests_events":
-			projectHook.MergeRequestsEvents = True
-		case "note_events":
-			projectHook.NoteEvents = True
-		case "pipeline_events":
-			projectHook.PipelineEvents = True
-		case "push_events":
-			projectHook.PushEvents = True
-		case "ReleasesEvents":
-			projectHook.ReleasesEvents = True
-		case "tag_push_events":
-			projectHook.TagPushEvents = True
-		case "wiki_page_events":
-			projectHook.WikiPageEvents = True
-		default:
-			return errors.New("unknown event type:" + event)
-		}
+// addGitLabEvents enables the native events of the GitWebhook on hook, a *projectHook or a *groupHook
+func (m *GitLabWebHook) addGitLabEvents(hook interface{}) error {
+	events, err := m.gitWebhook.GetNativeEvents()
+	if err != nil {
+		return err
 	}
-	return nil
-}
-
-func (m *GitLabWebHook) addGitLabEventsToAddProjectHookOptions(addProjectHookOptions *gitlab.AddProjectHookOptions) error {
-	True := true
-	for _, event := range m.gitWebhook.Spec.Events {
-		switch event {
-		case "confidential_issues_events":
-			addProjectHookOptions.ConfidentialIssuesEvents = &True
-		case "confidential_note_events":
-			addProjectHookOptions.ConfidentialNoteEvents = &True
-		case "deployment_events":
-			addProjectHookOptions.DeploymentEvents = &True
-		case "issues_events":
-			addProjectHookOptions.IssuesEvents = &True
-		case "job_events":
-			addProjectHookOptions.JobEvents = &True
-		case "merge_requests_events":
-			addProjectHookOptions.MergeRequestsEvents = &True
-		case "note_events":
-			addProjectHookOptions.NoteEvents = &True
-		case "pipeline_events":
-			addProjectHookOptions.PipelineEvents = &True
-		case "push_events":
-			addProjectHookOptions.PushEvents = &True
-		case "ReleasesEvents":
-			addProjectHookOptions.ReleasesEvents = &True
-		case "tag_push_events":
-			addProjectHookOptions.TagPushEvents = &True
-		case "wiki_page_events":
-			addProjectHookOptions.WikiPageEvents = &True
-		default:
-			return errors.New("unknown event type:" + event)
+	fields := reflect.ValueOf(hook).Elem()
+	for _, event := range events {
+		field := reflect.Value{}
+		if name, found := hookEventFields[event]; found {
+			field = fields.FieldByName(name)
 		}
-	}
-	return nil
-}
-
-func (m *GitLabWebHook) addGitLabEventsToEditProjectHookOptions(editProjectHookOptions *gitlab.EditProjectHookOptions) error {
-	True := true
-	for _, event := range m.gitWebhook.Spec.Events {
-		switch event {
-		case "confidential_issues_events":
-			editProjectHookOptions.ConfidentialIssuesEvents = &True
-		case "confidential_note_events":
-			editProjectHookOptions.ConfidentialNoteEvents = &True
-		case "deployment_events":
-			editProjectHookOptions.DeploymentEvents = &True
-		case "issues_events":
-			editProjectHookOptions.IssuesEvents = &True
-		case "job_events":
-			editProjectHookOptions.JobEvents = &True
-		case "merge_requests_events":
-			editProjectHookOptions.MergeRequestsEvents = &True
-		case "note_events":
-			editProjectHookOptions.NoteEvents = &True
-		case "pipeline_events":
-			editProjectHookOptions.PipelineEvents = &True
-		case "push_events":
-			editProjectHookOptions.PushEvents = &True
-		case "ReleasesEvents":
-			editProjectHookOptions.ReleasesEvents = &True
-		case "tag_push_events":
-			editProjectHookOptions.TagPushEvents = &True
-		case "wiki_page_events":
-			editProjectHookOptions.WikiPageEvents = &True
-		default:
+		if !field.IsValid() {
 			return errors.New("unknown event type:" + event)
 		}
+		field.SetBool(true)
 	}
 	return nil
 }
@@ -380,19 +407,19 @@ func (m *GitLabWebHook) getClient(ctx context.Context) (*gitlab.Client, error) {
 		log.Error(err, "Unable to retrieve gitlab credential", "secret", m.gitWebhook.Spec.GitLab.GitServerCredentials.Name)
 		return nil, err
 	}
-	var git *gitlab.Client
-	if m.gitWebhook.Spec.GitLab.GitLabAPIServerURL != "" {
-		git, err = gitlab.NewClient(token, gitlab.WithBaseURL(m.gitWebhook.Spec.GitLab.GitLabAPIServerURL))
-		if err != nil {
-			log.Error(err, "Failed to create gitlab client", "url", m.gitWebhook.Spec.GitLab.GitLabAPIServerURL)
-			return nil, err
-		}
-	} else {
-		git, err = gitlab.NewClient(token)
-		if err != nil {
-			log.Error(err, "Failed to create gitlab client")
-			return nil, err
-		}
+	git, err := newClient(token, m.gitWebhook.Spec.GitLab.GitLabAPIServerURL)
+	if err != nil {
+		log.Error(err, "Failed to create gitlab client", "url", m.gitWebhook.Spec.GitLab.GitLabAPIServerURL)
+		return nil, err
 	}
+	m.gitlab = git
 	return git, nil
 }
+
+// newClient creates a gitlab client, the default gitlab.com url is used when baseURL is empty
+func newClient(token string, baseURL string) (*gitlab.Client, error) {
+	if baseURL != "" {
+		return gitlab.NewClient(token, gitlab.WithBaseURL(baseURL))
+	}
+	return gitlab.NewClient(token)
+}
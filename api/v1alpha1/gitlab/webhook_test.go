@@ -0,0 +1,251 @@
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1"
+	"github.com/redhat-cop/gitwebhook-operator/api/v1alpha1/webhooktest"
+	"github.com/xanzy/go-gitlab"
+)
+
+// fakeServer serves the projects and the hooks of a gitlab instance, one hook per page so that the listings are paginated
+type fakeServer struct {
+	*webhooktest.Server
+	// projects by escaped full path
+	projects map[string]*gitlab.Project
+	// hooks by escaped hooks path, the hooks are stored as sent by the client
+	hooks  map[string][]map[string]interface{}
+	lastID int
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	s := &fakeServer{projects: map[string]*gitlab.Project{}, hooks: map[string][]map[string]interface{}{}, lastID: 100}
+	s.Server = webhooktest.NewServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		path := strings.TrimPrefix(r.URL.EscapedPath(), "/api/v4/")
+		hooksPath, id, _ := strings.Cut(path, "/hooks")
+		hooksPath += "/hooks"
+		id = strings.TrimPrefix(id, "/")
+		switch {
+		case path == "":
+			// the client reads the rate limit of the server when it is created
+		case r.Method == http.MethodGet && strings.HasPrefix(path, "groups/") && !strings.Contains(path, "/hooks"):
+			json.NewEncoder(w).Encode(&gitlab.Group{ID: 7})
+		case r.Method == http.MethodGet && strings.HasPrefix(path, "projects/") && !strings.Contains(path, "/hooks"):
+			for fullPath, project := range s.projects {
+				if path == "projects/"+fullPath || path == "projects/"+strconv.Itoa(project.ID) {
+					json.NewEncoder(w).Encode(project)
+					return
+				}
+			}
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodGet && strings.HasSuffix(id, "/events"):
+			// no delivery yet
+			json.NewEncoder(w).Encode([]interface{}{})
+		case r.Method == http.MethodGet && id == "":
+			page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+			if page == 0 {
+				page = 1
+			}
+			hooks := s.hooks[hooksPath]
+			result := []map[string]interface{}{}
+			if page <= len(hooks) {
+				result = append(result, hooks[page-1])
+			}
+			if page < len(hooks) {
+				w.Header().Set("X-Next-Page", strconv.Itoa(page+1))
+			}
+			json.NewEncoder(w).Encode(result)
+		case r.Method == http.MethodPost && id == "":
+			created := map[string]interface{}{}
+			json.NewDecoder(r.Body).Decode(&created)
+			s.lastID++
+			created["id"] = s.lastID
+			// gitlab does not return the token
+			delete(created, "token")
+			s.hooks[hooksPath] = append(s.hooks[hooksPath], created)
+			json.NewEncoder(w).Encode(created)
+		case r.Method == http.MethodPut:
+			for i, existing := range s.hooks[hooksPath] {
+				if strconv.Itoa(existing["id"].(int)) == id {
+					updated := map[string]interface{}{}
+					json.NewDecoder(r.Body).Decode(&updated)
+					updated["id"] = existing["id"]
+					delete(updated, "token")
+					s.hooks[hooksPath][i] = updated
+					json.NewEncoder(w).Encode(updated)
+					return
+				}
+			}
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodDelete:
+			for i, existing := range s.hooks[hooksPath] {
+				if strconv.Itoa(existing["id"].(int)) == id {
+					s.hooks[hooksPath] = append(s.hooks[hooksPath][:i], s.hooks[hooksPath][i+1:]...)
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+			}
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			webhooktest.UnexpectedRequest(t, w, r)
+		}
+	})
+	return s
+}
+
+func newTestWebHook(t *testing.T, server *fakeServer, gitWebhook *redhatcopv1alpha1.GitWebhook) *GitLabWebHook {
+	git, err := gitlab.NewClient("token", gitlab.WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &GitLabWebHook{gitWebhook: gitWebhook, gitlab: git}
+}
+
+func newTestGitWebhook(serverURL string, repositoryOwner string, repositoryName string) *redhatcopv1alpha1.GitWebhook {
+	gitWebhook := &redhatcopv1alpha1.GitWebhook{}
+	gitWebhook.Name = "hook"
+	gitWebhook.Namespace = "default"
+	gitWebhook.Spec = redhatcopv1alpha1.GitWebhookSpec{
+		GitLab:          &redhatcopv1alpha1.GitLabServerConfig{GitLabAPIServerURL: serverURL},
+		OwnerType:       "organization",
+		RepositoryOwner: repositoryOwner,
+		RepositoryName:  repositoryName,
+		WebhookURL:      "https://hooks.example.com/app",
+		Events:          []string{"push_events"},
+	}
+	// the hooks have no secret
+	gitWebhook.SetAppliedWebhookSecret("")
+	return gitWebhook
+}
+
+func TestGetProject(t *testing.T) {
+	server := newFakeServer(t)
+	server.projects["platform%2Fteam%2Fapp"] = &gitlab.Project{ID: 1, PathWithNamespace: "platform/team/app"}
+	server.projects["platform%2F2024"] = &gitlab.Project{ID: 2, PathWithNamespace: "platform/2024"}
+	server.projects["platform%2Fother"] = &gitlab.Project{ID: 42, PathWithNamespace: "platform/other"}
+	tests := []struct {
+		name            string
+		repositoryOwner string
+		repositoryName  string
+		wantFound       bool
+		wantID          int
+	}{
+		{name: "nested subgroup path", repositoryOwner: "platform/team", repositoryName: "app", wantFound: true, wantID: 1},
+		{name: "project named with digits", repositoryOwner: "platform", repositoryName: "2024", wantFound: true, wantID: 2},
+		{name: "numeric id", repositoryOwner: "platform", repositoryName: "42", wantFound: true, wantID: 42},
+		{name: "missing project", repositoryOwner: "platform", repositoryName: "missing"},
+		{name: "missing project id", repositoryOwner: "platform", repositoryName: "43"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			project, found, err := newTestWebHook(t, server, newTestGitWebhook(server.URL, test.repositoryOwner, test.repositoryName)).getProject(context.TODO())
+			if err != nil {
+				t.Fatalf("getProject() error = %v", err)
+			}
+			if found != test.wantFound || (found && project.ID != test.wantID) {
+				t.Errorf("getProject() = %+v, %v, want %d, %v", project, found, test.wantID, test.wantFound)
+			}
+		})
+	}
+}
+
+func TestGroupHookLifecycle(t *testing.T) {
+	const hooksPath = "groups/platform%2Fteam/hooks"
+	server := newFakeServer(t)
+	server.hooks[hooksPath] = []map[string]interface{}{
+		{"id": 1, "url": "https://other.example.com/1", "push_events": true},
+		{"id": 2, "url": "https://other.example.com/2", "push_events": true},
+	}
+	gitWebhook := newTestGitWebhook(server.URL, "platform/team", "")
+	gitWebhook.Spec.Events = []string{"push_events", "subgroup_events"}
+
+	webhooktest.RunLifecycle(t, server.Server, func() redhatcopv1alpha1.WebHook { return newTestWebHook(t, server, gitWebhook) },
+		// the hooks of the other receivers are listed over several pages
+		webhooktest.Step{
+			Name:      "create",
+			Mutations: []string{"POST /api/v4/" + hooksPath},
+			Check: func(t *testing.T, webHook redhatcopv1alpha1.WebHook) {
+				hooks := server.hooks[hooksPath]
+				if len(hooks) != 3 || hooks[2]["url"] != "https://hooks.example.com/app" || hooks[2]["subgroup_events"] != true || hooks[2]["member_events"] != false {
+					t.Fatalf("create: hook not created as expected: %+v", hooks)
+				}
+				if gitWebhook.Status.HookID != "101" {
+					t.Fatalf("create: hookID = %q, want 101", gitWebhook.Status.HookID)
+				}
+				if applied := webHook.(*GitLabWebHook).AppliedWebHook(); applied == nil || applied.RepositoryID != "7" {
+					t.Fatalf("create: applied webhook = %+v, want group 7", applied)
+				}
+			},
+		},
+		// the owned hook is found on the last page
+		webhooktest.Step{Name: "resync"},
+		// the events removed from the spec are disabled
+		webhooktest.Step{
+			Name:      "update",
+			Change:    func() { gitWebhook.Spec.Events = []string{"push_events", "member_events"} },
+			Mutations: []string{"PUT /api/v4/" + hooksPath + "/101"},
+			Check: func(t *testing.T, _ redhatcopv1alpha1.WebHook) {
+				hooks := server.hooks[hooksPath]
+				if len(hooks) != 3 || hooks[2]["subgroup_events"] != false || hooks[2]["member_events"] != true || hooks[2]["id"] != 101 {
+					t.Fatalf("update: hook not updated as expected: %+v", hooks[2])
+				}
+			},
+		},
+		webhooktest.Step{Name: "resync after update"},
+		// the hooks of the other receivers are left alone
+		webhooktest.Step{
+			Name:      "delete",
+			Delete:    true,
+			Mutations: []string{"DELETE /api/v4/" + hooksPath + "/101"},
+			Check: func(t *testing.T, _ redhatcopv1alpha1.WebHook) {
+				hooks := server.hooks[hooksPath]
+				if len(hooks) != 2 || hooks[0]["id"] != 1 || hooks[1]["id"] != 2 {
+					t.Fatalf("delete: unexpected remaining hooks %+v", hooks)
+				}
+			},
+		},
+	)
+}
+
+func TestAddGitLabEvents(t *testing.T) {
+	tests := []struct {
+		name    string
+		hook    interface{}
+		events  []string
+		wantErr bool
+	}{
+		{name: "project hook", hook: &projectHook{}, events: []string{"push_events", "releases_events", "emoji_events"}},
+		{name: "group hook", hook: &groupHook{}, events: []string{"push_events", "member_events", "subgroup_events"}},
+		{name: "group event on a project hook", hook: &projectHook{}, events: []string{"member_events"}, wantErr: true},
+		{name: "unknown event", hook: &groupHook{}, events: []string{"unknown_events"}, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := &GitLabWebHook{gitWebhook: &redhatcopv1alpha1.GitWebhook{}}
+			m.gitWebhook.Spec.GitLab = &redhatcopv1alpha1.GitLabServerConfig{}
+			m.gitWebhook.Spec.Events = test.events
+			err := m.addGitLabEvents(test.hook)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("addGitLabEvents() error = %v, want error %v", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			// the hooks are compared in their json form, in which the events have their gitlab names
+			data, _ := json.Marshal(test.hook)
+			fields := map[string]interface{}{}
+			json.Unmarshal(data, &fields)
+			for _, event := range test.events {
+				if fields[event] != true {
+					t.Errorf("%s not enabled in %s", event, data)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"errors"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// GitLabSystemHookSpec defines the desired state of GitLabSystemHook
+type GitLabSystemHookSpec struct {
+
+	// GitLab the configuration to connect to the self-managed gitlab instance, the credentials must belong to an administrator
+	// +kubebuilder:validation:Required
+	GitLab GitLabInstanceServerConfig `json:"gitLab"`
+
+	// WebhookURL The URL of the system hook to be called
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^https?:\/\/(?:www\.)?[-a-zA-Z0-9@:%._\+~#=]{1,256}\.[a-zA-Z0-9()]{1,6}\b(?:[-a-zA-Z0-9()@:%_\+.~#?&\/=]*)$`
+	WebhookURL string `json:"webhookURL,omitempty"`
+
+	// InsecureSSL whether to not verify the certificate of the server serving the system hook
+	InsecureSSL bool `json:"insecureSSL,omitempty"`
+
+	// WebhookSecret The secret to be used in the system hook callbacks. The key "secret" will be used to retrieve the secret/token
+	WebhookSecret corev1.SecretReference `json:"webhookSecret,omitempty"`
+
+	// Events The list of events that this system hook should be notified for, in addition to the system events that gitlab always sends
+	// +listType=set
+	// +kubebuilder:validation:items:Enum="push_events";"tag_push_events";"merge_requests_events";"repository_update_events"
+	Events []string `json:"events,omitempty"`
+
+	// AdoptionPolicy what to do with a system hook that has the url of this GitLabSystemHook but was not created by it: take it over (Adopt), report a conflict (FailIfExists) or leave it alone and create another system hook (Ignore).
+	// The system hook created or adopted is tracked by its id in the status, an adoption is reported with a WebhookAdopted event
+	// +kubebuilder:validation:Enum=Adopt;FailIfExists;Ignore
+	// +kubebuilder:default=Adopt
+	AdoptionPolicy string `json:"adoptionPolicy,omitempty"`
+}
+
+// GitLabInstanceServerConfig the configuration to connect to a gitlab instance from a cluster scoped resource
+type GitLabInstanceServerConfig struct {
+	// GitLabAPIServerURL the url of the gitlab instance api
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^https?:\/\/(?:www\.)?[-a-zA-Z0-9@:%._\+~#=]{1,256}\.[a-zA-Z0-9()]{1,6}\b(?:[-a-zA-Z0-9()@:%_\+.~#?&\/=]*)$`
+	GitLabAPIServerURL string `json:"gitLabAPIServerURL,omitempty"`
+	// GitServerCredentials credentials to use when authenticating to the git server, must contain a "token" key
+	// +kubebuilder:validation:Required
+	GitServerCredentials corev1.SecretReference `json:"gitServerCredentials,omitempty"`
+}
+
+// GitLabSystemHookStatus defines the observed state of GitLabSystemHook
+type GitLabSystemHookStatus struct {
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+	// HookID the id on the git server of the system hook created or adopted by this GitLabSystemHook
+	HookID string `json:"hookID,omitempty"`
+	// WebhookSecretHash a salted sha256 of the webhook secret last applied to the git server, the git server does not return the secret so it is used to detect secret changes
+	WebhookSecretHash string `json:"webhookSecretHash,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`
+//+kubebuilder:printcolumn:name="Message",type=string,priority=1,JSONPath=`.status.conditions[?(@.type=="Ready")].message`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// GitLabSystemHook is the Schema for the gitlabsystemhooks API
+type GitLabSystemHook struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GitLabSystemHookSpec   `json:"spec,omitempty"`
+	Status GitLabSystemHookStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// GitLabSystemHookList contains a list of GitLabSystemHook
+type GitLabSystemHookList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GitLabSystemHook `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GitLabSystemHook{}, &GitLabSystemHookList{})
+}
+
+func (m *GitLabSystemHook) GetConditions() []metav1.Condition {
+	return m.Status.Conditions
+}
+
+func (m *GitLabSystemHook) SetConditions(conditions []metav1.Condition) {
+	m.Status.Conditions = conditions
+}
+
+func (m *GitLabSystemHook) GetWebhookSecret(ctx context.Context) (string, error) {
+	if m.Spec.WebhookSecret.Name == "" {
+		return "", nil
+	}
+	return getSecretKey(ctx, m.Spec.WebhookSecret, "secret")
+}
+
+func (m *GitLabSystemHook) GetGitCredential(ctx context.Context) (string, error) {
+	return getSecretKey(ctx, m.Spec.GitLab.GitServerCredentials, "token")
+}
+
+// getSecretKey reads a key of a secret referenced by a cluster scoped resource
+func getSecretKey(ctx context.Context, secretReference corev1.SecretReference, key string) (string, error) {
+	log := log.FromContext(ctx)
+	kubeClient := ctx.Value("kubeClient").(client.Client)
+	secret := &corev1.Secret{}
+	err := kubeClient.Get(ctx, types.NamespacedName{
+		Name:      secretReference.Name,
+		Namespace: secretReference.Namespace,
+	}, secret, &client.GetOptions{})
+	if err != nil {
+		log.Error(err, "unable to find secret: "+secretReference.Namespace+"/"+secretReference.Name)
+		return "", secretError(secretReference.Namespace+"/"+secretReference.Name, err)
+	}
+	if data, found := secret.Data[key]; !found {
+		return "", errors.New("\"" + key + "\" key not found in secret " + secretReference.Namespace + "/" + secretReference.Name)
+	} else {
+		return string(data), nil
+	}
+}
+
+// GetReferencedSecrets returns the secrets read to reconcile the system hook
+func (m *GitLabSystemHook) GetReferencedSecrets() []corev1.SecretReference {
+	return []corev1.SecretReference{m.Spec.WebhookSecret, m.Spec.GitLab.GitServerCredentials}
+}
+
+// IsOwnedHook returns whether the system hook with the given id was created or adopted by the GitLabSystemHook
+func (m *GitLabSystemHook) IsOwnedHook(hookID string) bool {
+	return hookID != "" && hookID == m.Status.HookID
+}
+
+// GetHookID returns the id of the system hook created or adopted by the GitLabSystemHook
+func (m *GitLabSystemHook) GetHookID() string {
+	return m.Status.HookID
+}
+
+// SetOwnedHook records the id of the system hook created or adopted by the GitLabSystemHook
+func (m *GitLabSystemHook) SetOwnedHook(hookID string) {
+	m.Status.HookID = hookID
+}
+
+// MatchesWebhookURL returns whether a system hook found on the git server has the url of the GitLabSystemHook
+func (m *GitLabSystemHook) MatchesWebhookURL(hookURL string, webhookURL string) bool {
+	return hookURL == webhookURL
+}
+
+// ClaimHook decides as per the adoption policy whether a system hook that has the url of the GitLabSystemHook but was not created by it is managed by the GitLabSystemHook
+func (m *GitLabSystemHook) ClaimHook(hookID string) (bool, error) {
+	if m.IsOwnedHook(hookID) {
+		return true, nil
+	}
+	claimed, err := claimHook(m.Spec.AdoptionPolicy, !m.DeletionTimestamp.IsZero(), m.Status.HookID, hookID)
+	if claimed {
+		m.SetOwnedHook(hookID)
+	}
+	return claimed, err
+}
+
+// SetAppliedWebhookSecret records the fingerprint of the webhook secret applied to the git server
+func (m *GitLabSystemHook) SetAppliedWebhookSecret(secret string) {
+	m.Status.WebhookSecretHash = saltedHash(m.GetUID(), secret)
+}
+
+// IsWebhookSecretApplied returns whether the webhook secret is the one last applied to the git server
+func (m *GitLabSystemHook) IsWebhookSecretApplied(secret string) bool {
+	return saltedHash(m.GetUID(), secret) == m.Status.WebhookSecretHash
+}
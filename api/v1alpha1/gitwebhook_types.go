@@ -18,9 +18,14 @@ package v1alpha1
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"regexp"
+	"strings"
 
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -37,20 +42,41 @@ type WebHook interface {
 // NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
 
 // GitWebhookSpec defines the desired state of GitWebhook
+// +kubebuilder:validation:XValidation:rule="!has(self.webhookURL) || !self.webhookURL.matches('[{}]') || has(self.gitLab)",message="webhookURL placeholders are only supported by gitlab url variables"
 type GitWebhookSpec struct {
 
-	// GitLab the configuration to connect to the gitlab server. only one of gitlab or github is allowed
+	// GitLab the configuration to connect to the gitlab server. only one of gitlab, github, bitbucket, bitbucketDataCenter, gitea, azureDevOps, gerrit or custom is allowed
 	GitLab *GitLabServerConfig `json:"gitLab,omitempty"`
 
 	// GitHub the configuration to connect to the gitlab server
 	GitHub *GitHubServerConfig `json:"gitHub,omitempty"`
 
+	// Bitbucket the configuration to connect to bitbucket cloud
+	Bitbucket *BitbucketServerConfig `json:"bitbucket,omitempty"`
+
+	// BitbucketDataCenter the configuration to connect to a bitbucket server or data center instance.
+	// RepositoryOwner is the project key and RepositoryName the repository slug
+	BitbucketDataCenter *BitbucketDataCenterServerConfig `json:"bitbucketDataCenter,omitempty"`
+
+	// Gitea the configuration to connect to a gitea or forgejo server
+	Gitea *GiteaServerConfig `json:"gitea,omitempty"`
+
+	// AzureDevOps the configuration to connect to azure devops. RepositoryOwner is the project and RepositoryName the repository
+	AzureDevOps *AzureDevOpsServerConfig `json:"azureDevOps,omitempty"`
+
+	// Gerrit the configuration to connect to a gerrit server with the webhooks plugin.
+	// RepositoryName is the project name and RepositoryOwner, when set, is the parent path of the project
+	Gerrit *GerritServerConfig `json:"gerrit,omitempty"`
+
+	// Custom the configuration to connect to a git server supported by a provider that is compiled in the operator but not built in
+	Custom *CustomServerConfig `json:"custom,omitempty"`
+
 	// RepositoryOwner The owner of the repository, can be either an organization or a user
 	// +kubebuilder:validation:Required
 	RepositoryOwner string `json:"repositoryOwner,omitempty"`
 
-	// RepositoryName The name of the repository
-	// +kubebuilder:validation:Required
+	// RepositoryName The name of the repository. When empty with ownerType organization, a github organization webhook or a gitlab group webhook is managed instead, which receives the events of all the repositories of the organization or group
+	// +kubebuilder:validation:Optional
 	RepositoryName string `json:"repositoryName,omitempty"`
 
 	// RepositoryName The name of the repository
@@ -59,31 +85,79 @@ type GitWebhookSpec struct {
 	// +kubebuilder:default="organization"
 	OwnerType string `json:"ownerType,omitempty"`
 
-	// WebhookURL The URL of the webhook to be called
-	// +kubebuilder:validation:Required
-	// +kubebuilder:validation:Pattern=`^https?:\/\/(?:www\.)?[-a-zA-Z0-9@:%._\+~#=]{1,256}\.[a-zA-Z0-9()]{1,6}\b(?:[-a-zA-Z0-9()@:%_\+.~#?&\/=]*)$`
+	// WebhookURL The URL of the webhook to be called, it can contain {placeholders} for the gitlab url variables, the other git servers reject them. Exactly one of webhookURL and webhookURLSecret must be set
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Pattern=`^https?:\/\/(?:www\.)?[-a-zA-Z0-9@:%._\+~#=]{1,256}\.[a-zA-Z0-9()]{1,6}\b(?:[-a-zA-Z0-9()@:%_\+.~#?&\/={}]*)$`
 	WebhookURL string `json:"webhookURL,omitempty"`
 
+	// WebhookURLSecret a local reference to a secret containing the URL of the webhook to be called in the "webhookURL" key, for urls that embed a credential. It cannot be changed once set, the url is changed in the secret instead
+	WebhookURLSecret corev1.LocalObjectReference `json:"webhookURLSecret,omitempty"`
+
 	// InsecureSSL whether to not verify the certificate of the server serving the webhook
 	InsecureSSL bool `json:"insecureSSL,omitempty"`
 
-	// WebhookSecret The secret to be used in the webhook callbacks. The key "secret" will be used to retrieve the secret/token
-	WebhookSecret corev1.LocalObjectReference `json:"webhookSecret,omitempty"`
+	// WebhookSecret The secret to be used in the webhook callbacks. The key "secret" will be used to retrieve the secret/token.
+	// When the secret does not exist, it is generated with a random value
+	WebhookSecret WebhookSecretReference `json:"webhookSecret,omitempty"`
 
-	// Events The list of events that this webbook should be notified for
+	// Events The list of events that this webbook should be notified for. The provider neutral events push, tag, pull_request, issue, comment, release, pipeline, deployment and wiki are translated to the native events of the git server, native events are passed through
 	// +listType=set
 	Events []string `json:"events,omitempty"`
 
-	// ContentType the content type of the webhook playload (github only, will be ignored for gitlab)
+	// ContentType the content type of the webhook playload (github and gitea only, will be ignored for the other git servers)
 	// +kubebuilder:default="json"
 	ContentType string `json:"content,omitempty"`
 
-	// Active whether this webhook should be actibe (github only, will be ignored for gitlab)
+	// Active whether this webhook should be actibe (will be ignored for gitlab)
 	// +kubebuilder:default=true
 	Active bool `json:"active,omitempty"`
 
 	// PushEventBranchFilter filter for push event on branches (gitlab only, will be ignored for github)
 	PushEventBranchFilter string `json:"pushEventBranchFilter,omitempty"`
+
+	// DeletionPolicy whether the webhook is deleted from the git server when the GitWebhook is deleted (Delete), or left in place (Retain)
+	// +kubebuilder:default=Delete
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// AdoptionPolicy what to do with a webhook that has the url of this GitWebhook but was not created by it: take it over (Adopt), report a conflict (FailIfExists) or leave it alone and create another webhook (Ignore).
+	// The webhook created or adopted is tracked by its id in the status, an adoption is reported with a WebhookAdopted event. Only Adopt is supported by gerrit and azure devops
+	// +kubebuilder:validation:Enum=Adopt;FailIfExists;Ignore
+	// +kubebuilder:default=Adopt
+	AdoptionPolicy string `json:"adoptionPolicy,omitempty"`
+
+	// ResyncInterval how often the webhook is compared with the git server, to repair the changes made outside of the operator.
+	// It defaults to the --resync-interval flag of the operator, 0s disables the periodic resync
+	ResyncInterval *metav1.Duration `json:"resyncInterval,omitempty"`
+}
+
+// DeletionPolicy what happens to the webhook on the git server when the GitWebhook is deleted
+// +kubebuilder:validation:Enum=Delete;Retain
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete deletes the webhook from the git server
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+	// DeletionPolicyRetain leaves the webhook on the git server, for example while the GitWebhook is moved to another cluster
+	DeletionPolicyRetain DeletionPolicy = "Retain"
+)
+
+type WebhookSecretReference struct {
+	// Name the name of the secret, it defaults to "<name of the GitWebhook>-webhook-secret" when generate is true
+	Name string `json:"name,omitempty"`
+	// Generate whether to generate a secret named "<name of the GitWebhook>-webhook-secret" when name is omitted
+	Generate bool `json:"generate,omitempty"`
+	// Rotation enables the rotation of the secret, periodically or on demand with the "gitwebhook.redhatcop.redhat.io/rotate-secret" annotation.
+	// Only the secrets generated by the operator are rotated, unless the secret is annotated with "gitwebhook.redhatcop.redhat.io/allow-rotation: true"
+	Rotation *WebhookSecretRotation `json:"rotation,omitempty"`
+}
+
+type WebhookSecretRotation struct {
+	// Interval the time between two rotations, the secret is only rotated on demand when omitted
+	Interval *metav1.Duration `json:"interval,omitempty"`
+	// GracePeriod how long the previous value of the secret remains available in the "previousSecret" key after the rotated value has been applied to the git server,
+	// so that the receivers can accept both signatures while the calls sent before the update are delivered
+	// +kubebuilder:default="1h"
+	GracePeriod metav1.Duration `json:"gracePeriod,omitempty"`
 }
 
 type GitHubServerConfig struct {
@@ -91,7 +165,7 @@ type GitHubServerConfig struct {
 	// +kubebuilder:validation:Pattern=`^https?:\/\/(?:www\.)?[-a-zA-Z0-9@:%._\+~#=]{1,256}\.[a-zA-Z0-9()]{1,6}\b(?:[-a-zA-Z0-9()@:%_\+.~#?&\/=]*)$`
 	// +kubebuilder:default="https://api.github.com/"
 	GitHubAPIServerURL string `json:"gitHubAPIServerURL,omitempty"`
-	// GitServerCredentials credentials to use when authenticating to the git server, must contain a "token" key
+	// GitServerCredentials credentials to use when authenticating to the git server, must contain either a "token" key or the "appID", "privateKey" and optionally "installationID" keys of a github app
 	GitServerCredentials corev1.LocalObjectReference `json:"gitServerCredentials,omitempty"`
 }
 
@@ -102,10 +176,164 @@ type GitLabServerConfig struct {
 	GitLabAPIServerURL string `json:"gitLabAPIServerURL,omitempty"`
 	// GitServerCredentials credentials to use when authenticating to the git server, must contain a "token" key
 	GitServerCredentials corev1.LocalObjectReference `json:"gitServerCredentials,omitempty"`
+	// HookName the name of the webhook shown by gitlab
+	HookName string `json:"hookName,omitempty"`
+	// HookDescription the description of the webhook shown by gitlab
+	HookDescription string `json:"hookDescription,omitempty"`
+	// BranchFilterStrategy how pushEventBranchFilter is matched against the branches, gitlab defaults to wildcard
+	// +kubebuilder:validation:Enum=wildcard;regex;all_branches
+	BranchFilterStrategy string `json:"branchFilterStrategy,omitempty"`
+	// CustomHeaders headers added to the webhook requests. Gitlab does not return the header values, so changes to the values alone are not detected
+	// +listType=map
+	// +listMapKey=key
+	CustomHeaders []GitLabCustomHeader `json:"customHeaders,omitempty"`
+	// CustomWebhookTemplate a custom payload template, replacing the default payload of the webhook requests
+	CustomWebhookTemplate string `json:"customWebhookTemplate,omitempty"`
+	// URLVariablesSecret a local reference to a secret containing the values of the {placeholders} of webhookURL, keyed by placeholder name.
+	// Gitlab masks the url variables, so that the values do not appear in the webhook url
+	URLVariablesSecret corev1.LocalObjectReference `json:"urlVariablesSecret,omitempty"`
+}
+
+type GitLabCustomHeader struct {
+	// Key the name of the header
+	// +kubebuilder:validation:Required
+	Key string `json:"key"`
+	// Value the value of the header
+	// +kubebuilder:validation:Required
+	Value string `json:"value"`
+}
+
+type BitbucketServerConfig struct {
+	// BitbucketAPIServerURL the url of the bitbucket cloud api
+	// +kubebuilder:validation:Pattern=`^https?:\/\/(?:www\.)?[-a-zA-Z0-9@:%._\+~#=]{1,256}\.[a-zA-Z0-9()]{1,6}\b(?:[-a-zA-Z0-9()@:%_\+.~#?&\/=]*)$`
+	// +kubebuilder:default="https://api.bitbucket.org/2.0/"
+	BitbucketAPIServerURL string `json:"bitbucketAPIServerURL,omitempty"`
+	// GitServerCredentials credentials to use when authenticating to the git server, must contain a "token" key with a workspace or repository access token.
+	// When authenticating with an app password, the "token" key contains the app password and the "username" key the bitbucket username
+	GitServerCredentials corev1.LocalObjectReference `json:"gitServerCredentials,omitempty"`
+}
+
+type BitbucketDataCenterServerConfig struct {
+	// BitbucketDataCenterAPIServerURL the url of the bitbucket data center server, for example https://bitbucket.example.com/
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^https?:\/\/(?:www\.)?[-a-zA-Z0-9@:%._\+~#=]{1,256}\.[a-zA-Z0-9()]{1,6}\b(?:[-a-zA-Z0-9()@:%_\+.~#?&\/=]*)$`
+	BitbucketDataCenterAPIServerURL string `json:"bitbucketDataCenterAPIServerURL"`
+	// GitServerCredentials credentials to use when authenticating to the git server, must contain a "token" key with an http access token.
+	// When authenticating with a password, the "token" key contains the password and the "username" key the bitbucket username
+	GitServerCredentials corev1.LocalObjectReference `json:"gitServerCredentials,omitempty"`
+}
+
+type GiteaServerConfig struct {
+	// GiteaAPIServerURL the url of the gitea or forgejo server, for example https://gitea.example.com/
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^https?:\/\/(?:www\.)?[-a-zA-Z0-9@:%._\+~#=]{1,256}\.[a-zA-Z0-9()]{1,6}\b(?:[-a-zA-Z0-9()@:%_\+.~#?&\/=]*)$`
+	GiteaAPIServerURL string `json:"giteaAPIServerURL"`
+	// GitServerCredentials credentials to use when authenticating to the git server, must contain a "token" key
+	GitServerCredentials corev1.LocalObjectReference `json:"gitServerCredentials,omitempty"`
+	// Type the type of the webhook, which determines the payload format. The chat integrations need settings that are not managed, so only gitea and gogs are supported. It cannot be changed after creation
+	// +kubebuilder:validation:Enum="gitea";"gogs"
+	// +kubebuilder:default="gitea"
+	Type string `json:"type,omitempty"`
+	// BranchFilter glob pattern to filter from which branches push, branch creation and branch deletion events should be generated
+	BranchFilter string `json:"branchFilter,omitempty"`
+	// AuthorizationHeaderSecret secret containing the value of the Authorization header sent with the webhook calls. The key "authorizationHeader" will be used to retrieve the value
+	AuthorizationHeaderSecret corev1.LocalObjectReference `json:"authorizationHeaderSecret,omitempty"`
+}
+
+type AzureDevOpsServerConfig struct {
+	// AzureDevOpsAPIServerURL the url of the azure devops server
+	// +kubebuilder:validation:Pattern=`^https?:\/\/(?:www\.)?[-a-zA-Z0-9@:%._\+~#=]{1,256}\.[a-zA-Z0-9()]{1,6}\b(?:[-a-zA-Z0-9()@:%_\+.~#?&\/=]*)$`
+	// +kubebuilder:default="https://dev.azure.com/"
+	AzureDevOpsAPIServerURL string `json:"azureDevOpsAPIServerURL,omitempty"`
+	// Organization the azure devops organization, or the collection for azure devops server
+	// +kubebuilder:validation:Required
+	Organization string `json:"organization"`
+	// GitServerCredentials credentials to use when authenticating to the git server, must contain a "token" key with a personal access token
+	GitServerCredentials corev1.LocalObjectReference `json:"gitServerCredentials,omitempty"`
+}
+
+type GerritServerConfig struct {
+	// GerritAPIServerURL the url of the gerrit server, for example https://gerrit.example.com/
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^https?:\/\/(?:www\.)?[-a-zA-Z0-9@:%._\+~#=]{1,256}\.[a-zA-Z0-9()]{1,6}\b(?:[-a-zA-Z0-9()@:%_\+.~#?&\/=]*)$`
+	GerritAPIServerURL string `json:"gerritAPIServerURL"`
+	// GitServerCredentials credentials to use when authenticating to the git server, must contain a "username" key and a "token" key with the http password
+	GitServerCredentials corev1.LocalObjectReference `json:"gitServerCredentials,omitempty"`
+	// RemoteName the name of the remote in the webhooks plugin configuration, defaults to <namespace>-<name> of the GitWebhook
+	RemoteName string `json:"remoteName,omitempty"`
+}
+
+// CustomServerConfig the configuration to connect to a git server supported by a provider registered with RegisterProvider
+type CustomServerConfig struct {
+	// Provider the name of the registered provider
+	// +kubebuilder:validation:Required
+	Provider string `json:"provider"`
+	// APIServerURL the url of the git server api
+	// +kubebuilder:validation:Pattern=`^https?:\/\/(?:www\.)?[-a-zA-Z0-9@:%._\+~#=]{1,256}\.[a-zA-Z0-9()]{1,6}\b(?:[-a-zA-Z0-9()@:%_\+.~#?&\/=]*)$`
+	APIServerURL string `json:"apiServerURL,omitempty"`
+	// GitServerCredentials credentials to use when authenticating to the git server, the required keys depend on the provider
+	GitServerCredentials corev1.LocalObjectReference `json:"gitServerCredentials,omitempty"`
+	// AdditionalSecrets other secrets that the provider reads, changes to these secrets trigger a reconcile
+	// +listType=atomic
+	AdditionalSecrets []corev1.LocalObjectReference `json:"additionalSecrets,omitempty"`
+	// Parameters provider specific settings
+	Parameters map[string]string `json:"parameters,omitempty"`
+}
+
+// AppliedWebhookSettings are the settings of the webhook last applied to the git server, as sent by the provider. The settings that the git server does not support are omitted
+type AppliedWebhookSettings struct {
+	// ContentType the content type of the webhook payload (github and gitea)
+	ContentType string `json:"contentType,omitempty"`
+	// Active whether the webhook is active (all but gitlab and gerrit)
+	Active *bool `json:"active,omitempty"`
+	// InsecureSSL whether the certificate of the server serving the webhook is not verified (all but gitea)
+	InsecureSSL *bool `json:"insecureSSL,omitempty"`
+	// PushEventBranchFilter the filter of the branches whose push events are notified (gitlab and gitea)
+	PushEventBranchFilter string `json:"pushEventBranchFilter,omitempty"`
+}
+
+// WebhookDelivery is the outcome of the last delivery of the webhook, as reported by the git server
+type WebhookDelivery struct {
+	// Code the http status code returned by the receiver, 0 when no delivery was made or when the receiver could not be reached
+	Code int `json:"code,omitempty"`
+	// Status the status of the webhook reported by the git server, for example "active" or "unused" for github and "executable" or "temporarily_disabled" for gitlab
+	Status string `json:"status,omitempty"`
+	// Message the message reported by the git server with the last delivery
+	Message string `json:"message,omitempty"`
+	// Failed whether the last delivery failed, or the git server disabled the webhook after failed deliveries
+	Failed bool `json:"failed,omitempty"`
 }
 
 // GitWebhookStatus defines the observed state of GitWebhook
 type GitWebhookStatus struct {
+	// ObservedGeneration the generation of the GitWebhook last applied to the git server
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// LastSyncTime when the webhook was last found or made in sync with the git server
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+	// Provider the git server the webhook is managed on
+	Provider string `json:"provider,omitempty"`
+	// RepositoryID the id on the git server of the repository, project, group or organization of the webhook, the name of the project for gerrit
+	RepositoryID string `json:"repositoryID,omitempty"`
+	// HookID the id on the git server of the webhook created or adopted by this GitWebhook
+	HookID string `json:"hookID,omitempty"`
+	// Events the native events of the git server that the webhook last applied is notified for, after the translation of the provider neutral events
+	Events []string `json:"events,omitempty"`
+	// Settings the settings of the webhook last applied to the git server
+	Settings *AppliedWebhookSettings `json:"settings,omitempty"`
+	// LastDelivery the outcome of the last delivery of the webhook as reported by the git server when the webhook was last reconciled (github and gitlab only)
+	LastDelivery *WebhookDelivery `json:"lastDelivery,omitempty"`
+	// WebhookURLHash a salted sha256 of the url of the webhook last applied to the git server, used to find the webhook when the url stored in webhookURLSecret changes
+	WebhookURLHash string `json:"webhookURLHash,omitempty"`
+	// WebhookSecretHash a salted sha256 of the webhook secret last applied to the git server, the git servers do not return the secret so it is used to detect secret changes
+	WebhookSecretHash string `json:"webhookSecretHash,omitempty"`
+	// LastSecretRotationTime when the webhook secret was last rotated, for information only as the rotation state is kept in the annotations of the secret
+	LastSecretRotationTime *metav1.Time `json:"lastSecretRotationTime,omitempty"`
+	// LastSecretRotationRequest the value of the "gitwebhook.redhatcop.redhat.io/rotate-secret" annotation when the webhook secret was last rotated on demand
+	LastSecretRotationRequest string `json:"lastSecretRotationRequest,omitempty"`
+	// LastDriftDetectedTime when the webhook on the git server was last found to differ from the GitWebhook, without the GitWebhook having changed
+	LastDriftDetectedTime *metav1.Time `json:"lastDriftDetectedTime,omitempty"`
+	// LastDriftCorrectedTime when the webhook on the git server was last brought back in line with the GitWebhook after a drift
+	LastDriftCorrectedTime *metav1.Time `json:"lastDriftCorrectedTime,omitempty"`
 	// +patchMergeKey=type
 	// +patchStrategy=merge
 	// +listType=map
@@ -115,6 +343,13 @@ type GitWebhookStatus struct {
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+//+kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`
+//+kubebuilder:printcolumn:name="Message",type=string,priority=1,JSONPath=`.status.conditions[?(@.type=="Ready")].message`
+//+kubebuilder:printcolumn:name="Hook ID",type=string,priority=1,JSONPath=`.status.hookID`
+//+kubebuilder:printcolumn:name="Delivery",type=string,priority=1,JSONPath=`.status.conditions[?(@.type=="DeliveryHealthy")].status`
+//+kubebuilder:printcolumn:name="Last Sync",type=date,JSONPath=`.status.lastSyncTime`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // GitWebhook is the Schema for the gitwebhooks API
 type GitWebhook struct {
@@ -138,58 +373,334 @@ func init() {
 	SchemeBuilder.Register(&GitWebhook{}, &GitWebhookList{})
 }
 
+func (m *GitWebhook) GetConditions() []metav1.Condition {
+	return m.Status.Conditions
+}
+
+func (m *GitWebhook) SetConditions(conditions []metav1.Condition) {
+	m.Status.Conditions = conditions
+}
+
+// GetWebhookSecretName returns the name of the webhook secret, defaulted when the secret is generated, or an empty string when the webhook has no secret
+func (m *GitWebhook) GetWebhookSecretName() string {
+	if m.Spec.WebhookSecret.Name == "" && m.Spec.WebhookSecret.Generate {
+		return m.GetName() + "-webhook-secret"
+	}
+	return m.Spec.WebhookSecret.Name
+}
+
 func (m *GitWebhook) GetWebhookSecret(ctx context.Context) (string, error) {
-	if m.Spec.WebhookSecret.Name == "" {
+	name := m.GetWebhookSecretName()
+	if name == "" {
 		return "", nil
 	}
 	log := log.FromContext(ctx)
 	kubeClient := ctx.Value("kubeClient").(client.Client)
 	secret := &corev1.Secret{}
 	err := kubeClient.Get(ctx, types.NamespacedName{
-		Name:      m.Spec.WebhookSecret.Name,
+		Name:      name,
 		Namespace: m.GetNamespace(),
 	}, secret, &client.GetOptions{})
 	if err != nil {
-		log.Error(err, "unable to find secret: "+m.Spec.WebhookSecret.Name)
-		return "", err
+		log.Error(err, "unable to find secret: "+name)
+		return "", secretError(name, err)
 	}
 	if data, found := secret.Data["secret"]; !found {
-		return "", errors.New("\"secret\" key not found in secret " + m.Spec.WebhookSecret.Name)
+		return "", errors.New("\"secret\" key not found in secret " + name)
 	} else {
 		return string(data), nil
 	}
 }
 
-func (m *GitWebhook) GetGitCredential(ctx context.Context, gitServerConfig interface{}) (string, error) {
-	var secretName string
-	switch v := gitServerConfig.(type) {
-	case *GitHubServerConfig:
-		{
-			secretName = v.GitServerCredentials.Name
-		}
-	case *GitLabServerConfig:
-		{
-			secretName = v.GitServerCredentials.Name
+func (m *GitWebhook) GetGiteaAuthorizationHeader(ctx context.Context) (string, error) {
+	if m.Spec.Gitea == nil || m.Spec.Gitea.AuthorizationHeaderSecret.Name == "" {
+		return "", nil
+	}
+	log := log.FromContext(ctx)
+	kubeClient := ctx.Value("kubeClient").(client.Client)
+	secret := &corev1.Secret{}
+	err := kubeClient.Get(ctx, types.NamespacedName{
+		Name:      m.Spec.Gitea.AuthorizationHeaderSecret.Name,
+		Namespace: m.GetNamespace(),
+	}, secret, &client.GetOptions{})
+	if err != nil {
+		log.Error(err, "unable to find secret: "+m.Spec.Gitea.AuthorizationHeaderSecret.Name)
+		return "", secretError(m.Spec.Gitea.AuthorizationHeaderSecret.Name, err)
+	}
+	if data, found := secret.Data["authorizationHeader"]; !found {
+		return "", errors.New("\"authorizationHeader\" key not found in secret " + m.Spec.Gitea.AuthorizationHeaderSecret.Name)
+	} else {
+		return string(data), nil
+	}
+}
+
+var urlVariablePattern = regexp.MustCompile(`{([^{}]+)}`)
+
+// webhookURLPattern is the validation pattern of webhookURL, applied to the urls read from webhookURLSecret
+var webhookURLPattern = regexp.MustCompile(`^https?:\/\/(?:www\.)?[-a-zA-Z0-9@:%._\+~#=]{1,256}\.[a-zA-Z0-9()]{1,6}\b(?:[-a-zA-Z0-9()@:%_\+.~#?&\/={}]*)$`)
+
+// GetWebhookURL returns the url of the webhook, read from the webhook url secret when one is referenced
+func (m *GitWebhook) GetWebhookURL(ctx context.Context) (string, error) {
+	if m.Spec.WebhookURLSecret.Name == "" {
+		return m.Spec.WebhookURL, nil
+	}
+	log := log.FromContext(ctx)
+	kubeClient := ctx.Value("kubeClient").(client.Client)
+	secret := &corev1.Secret{}
+	err := kubeClient.Get(ctx, types.NamespacedName{
+		Name:      m.Spec.WebhookURLSecret.Name,
+		Namespace: m.GetNamespace(),
+	}, secret, &client.GetOptions{})
+	if err != nil {
+		log.Error(err, "unable to find secret: "+m.Spec.WebhookURLSecret.Name)
+		return "", secretError(m.Spec.WebhookURLSecret.Name, err)
+	}
+	data, found := secret.Data["webhookURL"]
+	if !found {
+		return "", errors.New("\"webhookURL\" key not found in secret " + m.Spec.WebhookURLSecret.Name)
+	}
+	webhookURL := strings.TrimSpace(string(data))
+	// the url is not logged nor returned in the error, as it can embed a credential
+	if !webhookURLPattern.MatchString(webhookURL) {
+		return "", errors.New("\"webhookURL\" key of secret " + m.Spec.WebhookURLSecret.Name + " is not a valid url")
+	}
+	if len(URLVariableNames(webhookURL)) > 0 && (m.Spec.GitLab == nil || m.Spec.GitLab.URLVariablesSecret.Name == "") {
+		return "", errors.New("webhookURL placeholders require a gitlab urlVariablesSecret")
+	}
+	return webhookURL, nil
+}
+
+// hashWebhookURL salts the url with the uid of the GitWebhook, the url can embed a credential and the hash is stored in the status
+func (m *GitWebhook) hashWebhookURL(webhookURL string) string {
+	return saltedHash(m.GetUID(), webhookURL)
+}
+
+// SetAppliedWebhookURL records the url of the webhook applied to the git server
+func (m *GitWebhook) SetAppliedWebhookURL(webhookURL string) {
+	m.Status.WebhookURLHash = m.hashWebhookURL(webhookURL)
+}
+
+// IsWebhookURLApplied returns whether the webhook url is the one last applied to the git server
+func (m *GitWebhook) IsWebhookURLApplied(webhookURL string) bool {
+	return m.hashWebhookURL(webhookURL) == m.Status.WebhookURLHash
+}
+
+// hashWebhookSecret salts the secret with the uid of the GitWebhook, so that equal secrets do not have equal hashes
+func (m *GitWebhook) hashWebhookSecret(secret string) string {
+	return saltedHash(m.GetUID(), secret)
+}
+
+// saltedHash salts the value with the uid of the resource it belongs to, so that equal values do not have equal hashes
+func saltedHash(uid types.UID, value string) string {
+	if value == "" {
+		return ""
+	}
+	hash := sha256.Sum256([]byte(string(uid) + value))
+	return hex.EncodeToString(hash[:])
+}
+
+// SetAppliedWebhookSecret records the fingerprint of the webhook secret applied to the git server
+func (m *GitWebhook) SetAppliedWebhookSecret(secret string) {
+	m.Status.WebhookSecretHash = m.hashWebhookSecret(secret)
+}
+
+// IsWebhookSecretApplied returns whether the webhook secret is the one last applied to the git server
+func (m *GitWebhook) IsWebhookSecretApplied(secret string) bool {
+	return m.hashWebhookSecret(secret) == m.Status.WebhookSecretHash
+}
+
+// MatchesWebhookURL returns whether a webhook found on the git server is the one described by the GitWebhook,
+// either because it has the desired url, or because it has the url last applied before the url stored in webhookURLSecret changed
+func (m *GitWebhook) MatchesWebhookURL(hookURL string, webhookURL string) bool {
+	if hookURL == webhookURL {
+		return true
+	}
+	return m.Status.WebhookURLHash != "" && m.hashWebhookURL(hookURL) == m.Status.WebhookURLHash
+}
+
+// HookConflictError is returned when a webhook that has the url of the GitWebhook but was not created by it exists on the git server, and the adoption policy is FailIfExists
+// +kubebuilder:object:generate=false
+type HookConflictError struct {
+	HookID string
+}
+
+func (e *HookConflictError) Error() string {
+	return "webhook " + e.HookID + " already exists on the git server and is not managed by this resource, delete it or change the adoption policy"
+}
+
+// SecretNotFoundError is returned when a secret referenced by a resource does not exist
+// +kubebuilder:object:generate=false
+type SecretNotFoundError struct {
+	Name string
+	Err  error
+}
+
+func (e *SecretNotFoundError) Error() string {
+	return "secret " + e.Name + " not found"
+}
+
+func (e *SecretNotFoundError) Unwrap() error {
+	return e.Err
+}
+
+// secretError wraps the not found error of a secret read into a SecretNotFoundError, so that it is not mistaken for other missing resources
+func secretError(name string, err error) error {
+	if apierrors.IsNotFound(err) {
+		return &SecretNotFoundError{Name: name, Err: err}
+	}
+	return err
+}
+
+// IsOwnedHook returns whether the webhook with the given id was created or adopted by the GitWebhook
+func (m *GitWebhook) IsOwnedHook(hookID string) bool {
+	return hookID != "" && hookID == m.Status.HookID
+}
+
+// SetLastDelivery records the outcome of the last delivery of the webhook, nil when the git server does not report it
+func (m *GitWebhook) SetLastDelivery(delivery *WebhookDelivery) {
+	m.Status.LastDelivery = delivery
+}
+
+// SetOwnedHook records the id of the webhook created or adopted by the GitWebhook
+func (m *GitWebhook) SetOwnedHook(hookID string) {
+	m.Status.HookID = hookID
+}
+
+// ClaimHook decides as per the adoption policy whether a webhook that has the url of the GitWebhook but was not created by it is managed by the GitWebhook
+func (m *GitWebhook) ClaimHook(hookID string) (bool, error) {
+	if m.IsOwnedHook(hookID) {
+		return true, nil
+	}
+	claimed, err := claimHook(m.Spec.AdoptionPolicy, !m.DeletionTimestamp.IsZero(), m.Status.HookID, hookID)
+	if claimed {
+		m.SetOwnedHook(hookID)
+	}
+	return claimed, err
+}
+
+// claimHook decides as per the adoption policy whether a webhook that has the url of a resource but was not created by it is adopted by the resource.
+// While the resource is being deleted no webhook is adopted, except when no webhook is tracked yet, which is the case of the webhooks created before the ids were tracked
+func claimHook(adoptionPolicy string, deleting bool, trackedHookID string, hookID string) (bool, error) {
+	switch adoptionPolicy {
+	case "Ignore":
+		return false, nil
+	case "FailIfExists":
+		if deleting {
+			return false, nil
 		}
+		return false, &HookConflictError{HookID: hookID}
 	default:
-		{
-			return "", errors.New("unrecognized type")
+		if deleting && trackedHookID != "" {
+			return false, nil
 		}
+		return true, nil
+	}
+}
+
+// URLVariableNames returns the names of the {placeholders} of a webhook url, in order of appearance and without duplicates
+func URLVariableNames(webhookURL string) []string {
+	names := []string{}
+	seen := map[string]bool{}
+	for _, match := range urlVariablePattern.FindAllStringSubmatch(webhookURL, -1) {
+		if !seen[match[1]] {
+			seen[match[1]] = true
+			names = append(names, match[1])
+		}
+	}
+	return names
+}
+
+// GetGitLabURLVariables returns the values of the {placeholders} of the webhook url, read from the url variables secret
+func (m *GitWebhook) GetGitLabURLVariables(ctx context.Context, webhookURL string) (map[string]string, error) {
+	names := URLVariableNames(webhookURL)
+	if len(names) == 0 {
+		return nil, nil
+	}
+	if m.Spec.GitLab == nil || m.Spec.GitLab.URLVariablesSecret.Name == "" {
+		return nil, errors.New("webhookURL placeholders require a gitlab urlVariablesSecret")
 	}
 	log := log.FromContext(ctx)
 	kubeClient := ctx.Value("kubeClient").(client.Client)
 	secret := &corev1.Secret{}
 	err := kubeClient.Get(ctx, types.NamespacedName{
-		Name:      secretName,
+		Name:      m.Spec.GitLab.URLVariablesSecret.Name,
 		Namespace: m.GetNamespace(),
 	}, secret, &client.GetOptions{})
 	if err != nil {
-		log.Error(err, "unable to find secret: "+secretName)
+		log.Error(err, "unable to find secret: "+m.Spec.GitLab.URLVariablesSecret.Name)
+		return nil, secretError(m.Spec.GitLab.URLVariablesSecret.Name, err)
+	}
+	variables := map[string]string{}
+	for _, name := range names {
+		data, found := secret.Data[name]
+		if !found {
+			return nil, errors.New("\"" + name + "\" key not found in secret " + m.Spec.GitLab.URLVariablesSecret.Name)
+		}
+		variables[name] = string(data)
+	}
+	return variables, nil
+}
+
+func (m *GitWebhook) GetGitCredential(ctx context.Context, gitServerConfig ServerConfig) (string, error) {
+	secret, err := m.GetGitCredentialSecret(ctx, gitServerConfig)
+	if err != nil {
 		return "", err
 	}
 	if data, found := secret.Data["token"]; !found {
-		return "", errors.New("\"token\" key not found in secret " + m.Spec.WebhookSecret.Name)
+		return "", errors.New("\"token\" key not found in secret " + secret.Name)
 	} else {
 		return string(data), nil
 	}
 }
+
+// GetGitCredentialSecret returns the whole credential secret, for the git servers that need more than a token to authenticate.
+// The secret is checked against the credential keys of the provider.
+func (m *GitWebhook) GetGitCredentialSecret(ctx context.Context, gitServerConfig ServerConfig) (*corev1.Secret, error) {
+	secretName := gitServerConfig.GetGitServerCredentials().Name
+	log := log.FromContext(ctx)
+	kubeClient := ctx.Value("kubeClient").(client.Client)
+	secret := &corev1.Secret{}
+	err := kubeClient.Get(ctx, types.NamespacedName{
+		Name:      secretName,
+		Namespace: m.GetNamespace(),
+	}, secret, &client.GetOptions{})
+	if err != nil {
+		log.Error(err, "unable to find secret: "+secretName)
+		return nil, secretError(secretName, err)
+	}
+	provider, err := m.GetProvider()
+	if err != nil {
+		return nil, err
+	}
+	if !hasOneOfKeySets(secret, provider.CredentialKeys()) {
+		return nil, errors.New("secret " + secretName + " must contain the keys " + formatKeySets(provider.CredentialKeys()))
+	}
+	return secret, nil
+}
+
+func hasOneOfKeySets(secret *corev1.Secret, keySets [][]string) bool {
+	if len(keySets) == 0 {
+		return true
+	}
+	for _, keys := range keySets {
+		found := true
+		for _, key := range keys {
+			if _, ok := secret.Data[key]; !ok {
+				found = false
+				break
+			}
+		}
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+func formatKeySets(keySets [][]string) string {
+	formatted := []string{}
+	for _, keys := range keySets {
+		formatted = append(formatted, "\""+strings.Join(keys, "\", \"")+"\"")
+	}
+	return strings.Join(formatted, " or ")
+}
@@ -0,0 +1,128 @@
+package v1alpha1
+
+import (
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMatchesWebhookURL(t *testing.T) {
+	tests := []struct {
+		name       string
+		appliedURL string
+		hookURL    string
+		want       bool
+	}{
+		{name: "desired url", hookURL: "https://hooks.example.com/new", want: true},
+		{name: "other url", hookURL: "https://hooks.example.com/other", want: false},
+		{name: "url last applied", appliedURL: "https://hooks.example.com/old", hookURL: "https://hooks.example.com/old", want: true},
+		{name: "other url with an applied url", appliedURL: "https://hooks.example.com/old", hookURL: "https://hooks.example.com/other", want: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gitWebhook := &GitWebhook{}
+			if test.appliedURL != "" {
+				gitWebhook.SetAppliedWebhookURL(test.appliedURL)
+			}
+			if got := gitWebhook.MatchesWebhookURL(test.hookURL, "https://hooks.example.com/new"); got != test.want {
+				t.Errorf("MatchesWebhookURL() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestIsOwnedHook(t *testing.T) {
+	tests := []struct {
+		name   string
+		owned  string
+		hookID string
+		want   bool
+	}{
+		{name: "owned hook", owned: "42", hookID: "42", want: true},
+		{name: "other hook", owned: "42", hookID: "7", want: false},
+		{name: "no hook tracked", owned: "", hookID: "42", want: false},
+		{name: "empty id", owned: "", hookID: "", want: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gitWebhook := &GitWebhook{Status: GitWebhookStatus{HookID: test.owned}}
+			if got := gitWebhook.IsOwnedHook(test.hookID); got != test.want {
+				t.Errorf("IsOwnedHook() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestClaimHook(t *testing.T) {
+	tests := []struct {
+		name         string
+		policy       string
+		owned        string
+		hookID       string
+		deleting     bool
+		want         bool
+		wantConflict bool
+		wantOwned    string
+	}{
+		{name: "owned hook", policy: "FailIfExists", owned: "42", hookID: "42", want: true, wantOwned: "42"},
+		{name: "adopt by default", hookID: "7", want: true, wantOwned: "7"},
+		{name: "adopt replaces the tracked hook", policy: "Adopt", owned: "42", hookID: "7", want: true, wantOwned: "7"},
+		{name: "fail if exists", policy: "FailIfExists", hookID: "7", wantConflict: true},
+		{name: "ignore", policy: "Ignore", owned: "42", hookID: "7", want: false, wantOwned: "42"},
+		{name: "no adoption while deleting", policy: "Adopt", owned: "42", hookID: "7", deleting: true, want: false, wantOwned: "42"},
+		{name: "untracked hook deleted", policy: "Adopt", hookID: "7", deleting: true, want: true, wantOwned: "7"},
+		{name: "no conflict while deleting", policy: "FailIfExists", hookID: "7", deleting: true, want: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gitWebhook := &GitWebhook{
+				Spec:   GitWebhookSpec{AdoptionPolicy: test.policy},
+				Status: GitWebhookStatus{HookID: test.owned},
+			}
+			if test.deleting {
+				now := metav1.Now()
+				gitWebhook.DeletionTimestamp = &now
+			}
+			got, err := gitWebhook.ClaimHook(test.hookID)
+			var conflict *HookConflictError
+			if errors.As(err, &conflict) != test.wantConflict {
+				t.Fatalf("ClaimHook() error = %v, want conflict %v", err, test.wantConflict)
+			}
+			if got != test.want {
+				t.Errorf("ClaimHook() = %v, want %v", got, test.want)
+			}
+			if gitWebhook.Status.HookID != test.wantOwned {
+				t.Errorf("tracked hook = %q, want %q", gitWebhook.Status.HookID, test.wantOwned)
+			}
+		})
+	}
+}
+
+func TestValidateWebhookURLUpdate(t *testing.T) {
+	literal := GitWebhookSpec{WebhookURL: "https://hooks.example.com/app"}
+	inSecret := GitWebhookSpec{WebhookURLSecret: corev1.LocalObjectReference{Name: "url"}}
+	tests := []struct {
+		name    string
+		old     GitWebhookSpec
+		new     GitWebhookSpec
+		wantErr bool
+	}{
+		{name: "unchanged literal", old: literal, new: literal},
+		{name: "unchanged secret", old: inSecret, new: inSecret},
+		{name: "literal changed", old: literal, new: GitWebhookSpec{WebhookURL: "https://hooks.example.com/other"}, wantErr: true},
+		{name: "literal moved to a secret", old: literal, new: inSecret},
+		{name: "literal removed without a secret", old: literal, new: GitWebhookSpec{}, wantErr: true},
+		{name: "secret changed", old: inSecret, new: GitWebhookSpec{WebhookURLSecret: corev1.LocalObjectReference{Name: "other"}}, wantErr: true},
+		{name: "secret moved back to a literal", old: inSecret, new: literal, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := (&GitWebhook{Spec: test.new}).validateWebhookURLUpdate(&GitWebhook{Spec: test.old})
+			if (err != nil) != test.wantErr {
+				t.Errorf("validateWebhookURLUpdate() error = %v, wantErr %v", err, test.wantErr)
+			}
+		})
+	}
+}
@@ -56,7 +56,27 @@ var _ webhook.Validator = &GitWebhook{}
 func (r *GitWebhook) ValidateCreate() error {
 	gitwebhooklog.Info("validate create", "name", r.Name)
 
-	return r.validateOnlyOneGitServer()
+	err := r.validateOnlyOneGitServer()
+	if err != nil {
+		return err
+	}
+	err = r.validateRepositoryName()
+	if err != nil {
+		return err
+	}
+	err = r.validateWebhookURL()
+	if err != nil {
+		return err
+	}
+	err = r.validateURLVariables()
+	if err != nil {
+		return err
+	}
+	err = r.validateProviderSpec()
+	if err != nil {
+		return err
+	}
+	return r.validateEvents()
 }
 
 // ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
@@ -66,13 +86,41 @@ func (r *GitWebhook) ValidateUpdate(old runtime.Object) error {
 	if err != nil {
 		return err
 	}
+	err = r.validateRepositoryName()
+	if err != nil {
+		return err
+	}
+	err = r.validateWebhookURL()
+	if err != nil {
+		return err
+	}
+	err = r.validateURLVariables()
+	if err != nil {
+		return err
+	}
+	err = r.validateProviderSpec()
+	if err != nil {
+		return err
+	}
+	err = r.validateEvents()
+	if err != nil {
+		return err
+	}
 	oldGW := old.(*GitWebhook)
 	//owner,owertype, repository, git server and url cannot be changed and the git configuration
-	if r.Spec.GitHub != nil && oldGW.Spec.GitHub != nil && r.Spec.GitHub.GitHubAPIServerURL != oldGW.Spec.GitHub.GitHubAPIServerURL {
-		return errors.New("github server cannot be changed")
-	}
-	if r.Spec.GitLab != nil && oldGW.Spec.GitLab != nil && r.Spec.GitLab.GitLabAPIServerURL != oldGW.Spec.GitLab.GitLabAPIServerURL {
-		return errors.New("gitlab server cannot be changed")
+	provider, _ := r.GetProvider()
+	oldProvider, err := oldGW.GetProvider()
+	if err == nil && oldProvider.Name() == provider.Name() {
+		serverConfig, oldServerConfig := provider.ServerConfig(&r.Spec), oldProvider.ServerConfig(&oldGW.Spec)
+		if serverConfig.GetAPIServerURL() != oldServerConfig.GetAPIServerURL() {
+			return errors.New(provider.Name() + " server cannot be changed")
+		}
+		if validator, ok := serverConfig.(UpdateValidator); ok {
+			err = validator.ValidateUpdate(oldServerConfig)
+			if err != nil {
+				return err
+			}
+		}
 	}
 	if r.Spec.OwnerType != oldGW.Spec.OwnerType {
 		return errors.New("ownerType server cannot be changed")
@@ -83,8 +131,9 @@ func (r *GitWebhook) ValidateUpdate(old runtime.Object) error {
 	if r.Spec.RepositoryName != oldGW.Spec.RepositoryName {
 		return errors.New("repositoryName server cannot be changed")
 	}
-	if r.Spec.WebhookURL != oldGW.Spec.WebhookURL {
-		return errors.New("webhookURL server cannot be changed")
+	err = r.validateWebhookURLUpdate(oldGW)
+	if err != nil {
+		return err
 	}
 
 	// TODO(user): fill in your validation logic upon object update.
@@ -100,15 +149,91 @@ func (r *GitWebhook) ValidateDelete() error {
 }
 
 func (r *GitWebhook) validateOnlyOneGitServer() error {
-	count := 0
-	if r.Spec.GitHub != nil {
-		count++
+	_, err := r.GetProvider()
+	return err
+}
+
+// the url is either a literal or read from a secret
+func (r *GitWebhook) validateWebhookURL() error {
+	if (r.Spec.WebhookURL == "") == (r.Spec.WebhookURLSecret.Name == "") {
+		return errors.New("exactly one of webhookURL and webhookURLSecret must be set")
+	}
+	return nil
+}
+
+// the literal url cannot be changed but it can be moved to a secret, the secret reference cannot be changed once set, the url is changed in the secret instead
+func (r *GitWebhook) validateWebhookURLUpdate(old *GitWebhook) error {
+	movedToSecret := r.Spec.WebhookURL == "" && r.Spec.WebhookURLSecret.Name != "" && old.Spec.WebhookURLSecret.Name == ""
+	if r.Spec.WebhookURL != old.Spec.WebhookURL && !movedToSecret {
+		return errors.New("webhookURL cannot be changed, except to move it to webhookURLSecret")
+	}
+	if old.Spec.WebhookURLSecret.Name != "" && r.Spec.WebhookURLSecret.Name != old.Spec.WebhookURLSecret.Name {
+		return errors.New("webhookURLSecret cannot be changed, change the \"webhookURL\" key of the secret instead")
+	}
+	return nil
+}
+
+// webhookURL placeholders are only supported by gitlab url variables
+func (r *GitWebhook) validateURLVariables() error {
+	if len(URLVariableNames(r.Spec.WebhookURL)) == 0 {
+		return nil
+	}
+	if r.Spec.GitLab == nil || r.Spec.GitLab.URLVariablesSecret.Name == "" {
+		return errors.New("webhookURL placeholders require a gitlab urlVariablesSecret")
+	}
+	return nil
+}
+
+// repositoryName can be omitted only for the providers that support organization or group webhooks
+func (r *GitWebhook) validateRepositoryName() error {
+	if r.Spec.RepositoryName != "" {
+		return nil
+	}
+	provider, err := r.GetProvider()
+	if err != nil {
+		return err
+	}
+	if provider.SupportsOwnerWebHooks() && r.Spec.OwnerType == "organization" {
+		return nil
+	}
+	return errors.New("repositoryName is required, unless defining an organization webhook for a git server that supports it")
+}
+
+// validateProviderSpec rejects the fields and values that the provider does not support
+func (r *GitWebhook) validateProviderSpec() error {
+	provider, err := r.GetProvider()
+	if err != nil {
+		return err
+	}
+	validator, ok := provider.(SpecValidator)
+	if !ok {
+		return nil
+	}
+	return validator.ValidateSpec(&r.Spec)
+}
+
+// canonical events must be supported by the provider and native events must belong to its catalog, when the provider has one
+func (r *GitWebhook) validateEvents() error {
+	provider, err := r.GetProvider()
+	if err != nil {
+		return err
+	}
+	events, err := translateEvents(provider, r.Spec.Events)
+	if err != nil {
+		return err
+	}
+	catalog := provider.Events()
+	if catalog == nil {
+		return nil
 	}
-	if r.Spec.GitLab != nil {
-		count++
+	known := map[string]bool{}
+	for _, event := range catalog {
+		known[event] = true
 	}
-	if count != 1 {
-		return errors.New("exaclty one of gitlab and github must be initialized")
+	for _, event := range events {
+		if !known[event] {
+			return errors.New("unknown " + provider.Name() + " event type: " + event)
+		}
 	}
 	return nil
 }
@@ -0,0 +1,403 @@
+package v1alpha1
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Provider describes a git server supported by GitWebhook. Providers register themselves with RegisterProvider, usually from the init function of their package,
+// so that the controller and the webhook validation can dispatch to them without knowing them.
+// +kubebuilder:object:generate=false
+type Provider interface {
+	// Name the json name of the spec block of the provider, for example "gitHub"
+	Name() string
+	// ServerConfig returns the spec block of the provider, or nil when the GitWebhook targets another git server
+	ServerConfig(spec *GitWebhookSpec) ServerConfig
+	// NewWebHook returns the WebHook that manages on the git server the webhook described by the GitWebhook
+	NewWebHook(gitWebhook *GitWebhook) WebHook
+	// CredentialKeys returns the alternative sets of keys that the git server credential secret must contain
+	CredentialKeys() [][]string
+	// Events returns the catalog of the native events accepted by the provider, nil when any event is passed through to the git server
+	Events() []string
+	// CanonicalEvents returns the native events that each supported canonical event translates to
+	CanonicalEvents() map[string][]string
+	// SupportsOwnerWebHooks whether the provider can manage organization or group webhooks, when repositoryName is omitted
+	SupportsOwnerWebHooks() bool
+}
+
+// ServerConfig is implemented by the spec blocks of the providers
+// +kubebuilder:object:generate=false
+type ServerConfig interface {
+	GetAPIServerURL() string
+	GetGitServerCredentials() corev1.LocalObjectReference
+}
+
+// AdditionalSecretsReferencer can be implemented by the server configs that reference secrets other than the git server credentials, so that changes to those secrets trigger a reconcile
+// +kubebuilder:object:generate=false
+type AdditionalSecretsReferencer interface {
+	GetAdditionalSecrets() []corev1.LocalObjectReference
+}
+
+// UpdateValidator can be implemented by the server configs that have fields, other than the api server url, that cannot be changed once the webhook is created
+// +kubebuilder:object:generate=false
+type UpdateValidator interface {
+	ValidateUpdate(old ServerConfig) error
+}
+
+// SpecValidator can be implemented by the providers that do not support some fields or values of the GitWebhook spec, so that they are rejected rather than silently ignored
+// +kubebuilder:object:generate=false
+type SpecValidator interface {
+	ValidateSpec(spec *GitWebhookSpec) error
+}
+
+// WebhookSecretSupporter can be implemented by the providers whose git server may not support webhook secrets, no webhook secret is generated nor rotated for them
+// +kubebuilder:object:generate=false
+type WebhookSecretSupporter interface {
+	SupportsWebhookSecret() bool
+}
+
+// DriftDetector can be implemented by the webhooks that can tell, without changing anything on the git server, whether the webhook on the git server matches the GitWebhook,
+// so that the periodic resyncs only update the webhooks that drifted and the drifts get recorded
+// +kubebuilder:object:generate=false
+type DriftDetector interface {
+	IsEquivalent(ctx context.Context) (bool, error)
+}
+
+// AppliedWebHookReporter can be implemented by the webhooks that report what Reconcile applied to the git server, after the translation of the GitWebhook by the provider.
+// The spec of the GitWebhook is recorded in the status for the other webhooks
+// +kubebuilder:object:generate=false
+type AppliedWebHookReporter interface {
+	// AppliedWebHook returns the webhook that the last successful Reconcile created, updated or found in sync on the git server, nil otherwise
+	AppliedWebHook() *AppliedWebHook
+}
+
+// AppliedWebHook is the webhook applied to the git server, as reported by an AppliedWebHookReporter
+// +kubebuilder:object:generate=false
+type AppliedWebHook struct {
+	// RepositoryID the id on the git server of the repository, project, group or organization of the webhook
+	RepositoryID string
+	// Events the native events of the webhook
+	Events []string
+	// Settings the settings of the webhook, without the settings that the git server does not support
+	Settings AppliedWebhookSettings
+}
+
+// HookOwner is implemented by the resources that track by its id the webhook they created or adopted on the git server
+// +kubebuilder:object:generate=false
+type HookOwner interface {
+	IsOwnedHook(hookID string) bool
+	MatchesWebhookURL(hookURL string, webhookURL string) bool
+	ClaimHook(hookID string) (bool, error)
+}
+
+// HookAdopter can be implemented by the webhooks that adopt the existing webhooks of the git server as per the adoption policy, so that the controller reports the adoptions
+// +kubebuilder:object:generate=false
+type HookAdopter interface {
+	// AdoptedHookID returns the id of the webhook adopted by the last Reconcile, or an empty string when no webhook was adopted
+	AdoptedHookID() string
+}
+
+// HookAdoption records the webhook adopted by the HookFinders of a webhook, the webhooks embed it to implement HookAdopter
+// +kubebuilder:object:generate=false
+type HookAdoption struct {
+	adoptedHookID string
+}
+
+// AdoptedHookID returns the id of the webhook adopted by a HookFinder, or an empty string when no webhook was adopted
+func (a *HookAdoption) AdoptedHookID() string {
+	return a.adoptedHookID
+}
+
+// HookFinder finds the webhook of a HookOwner among the webhooks listed on the git server, page by page.
+// The webhook created or adopted by the owner wins over the other webhooks with the same url, the first of which is claimed as per the adoption policy once all the webhooks are listed
+// +kubebuilder:object:generate=false
+type HookFinder[H any] struct {
+	owner       HookOwner
+	adoption    *HookAdoption
+	webhookURL  string
+	candidate   H
+	candidateID string
+}
+
+// NewHookFinder returns a HookFinder for the webhooks of the owner with the given url, the webhook adopted by the owner is recorded in adoption
+func NewHookFinder[H any](owner HookOwner, adoption *HookAdoption, webhookURL string) *HookFinder[H] {
+	return &HookFinder[H]{
+		owner:      owner,
+		adoption:   adoption,
+		webhookURL: webhookURL,
+	}
+}
+
+// Owned returns whether the listed webhook is the one created or adopted by the owner, the listing can stop when it is
+func (f *HookFinder[H]) Owned(hook H, hookID string, hookURL string) bool {
+	if f.owner.IsOwnedHook(hookID) {
+		return true
+	}
+	if f.candidateID == "" && f.owner.MatchesWebhookURL(hookURL, f.webhookURL) {
+		f.candidate, f.candidateID = hook, hookID
+	}
+	return false
+}
+
+// Claim returns the webhook with the url of the owner when the owner claims it, to be called once all the webhooks are listed
+func (f *HookFinder[H]) Claim() (H, bool, error) {
+	var none H
+	if f.candidateID == "" {
+		return none, false, nil
+	}
+	claimed, err := f.owner.ClaimHook(f.candidateID)
+	if err != nil || !claimed {
+		return none, false, err
+	}
+	// the candidate is not the webhook created or adopted by the owner, which wins in Owned, claiming it adopts it
+	f.adoption.adoptedHookID = f.candidateID
+	return f.candidate, true, nil
+}
+
+// CanonicalEvents is the provider neutral event vocabulary, each provider translates these events to its native events.
+// Native event names are accepted too, and passed through untranslated.
+var CanonicalEvents = []string{
+	"push",
+	"tag",
+	"pull_request",
+	"issue",
+	"comment",
+	"release",
+	"pipeline",
+	"deployment",
+	"wiki",
+}
+
+func isCanonicalEvent(event string) bool {
+	for _, canonical := range CanonicalEvents {
+		if canonical == event {
+			return true
+		}
+	}
+	return false
+}
+
+var providers = []Provider{}
+
+// RegisterProvider makes a provider available to the controller and to the webhook validation, it panics when a provider with the same name is already registered
+func RegisterProvider(provider Provider) {
+	for _, registered := range providers {
+		if registered.Name() == provider.Name() {
+			panic("git webhook provider already registered: " + provider.Name())
+		}
+	}
+	providers = append(providers, provider)
+}
+
+// Providers returns the registered providers
+func Providers() []Provider {
+	return providers
+}
+
+func providerNames() string {
+	names := []string{}
+	for _, provider := range providers {
+		names = append(names, provider.Name())
+	}
+	return strings.Join(names, ", ")
+}
+
+// GetProvider returns the provider of the only spec block that is initialized
+func (m *GitWebhook) GetProvider() (Provider, error) {
+	var found Provider
+	for _, provider := range providers {
+		if provider.ServerConfig(&m.Spec) == nil {
+			continue
+		}
+		if found != nil {
+			return nil, errors.New("exactly one of " + providerNames() + " must be initialized")
+		}
+		found = provider
+	}
+	if found == nil {
+		if m.Spec.Custom != nil {
+			return nil, errors.New("custom provider " + m.Spec.Custom.Provider + " is not registered")
+		}
+		return nil, errors.New("exactly one of " + providerNames() + " must be initialized")
+	}
+	return found, nil
+}
+
+// SupportsWebhookSecret returns whether the git server of the GitWebhook supports webhook secrets
+func (m *GitWebhook) SupportsWebhookSecret() bool {
+	provider, err := m.GetProvider()
+	if err != nil {
+		return true
+	}
+	supporter, ok := provider.(WebhookSecretSupporter)
+	return !ok || supporter.SupportsWebhookSecret()
+}
+
+// GetNativeEvents returns the events of the spec translated to the native events of the provider, without duplicates
+func (m *GitWebhook) GetNativeEvents() ([]string, error) {
+	provider, err := m.GetProvider()
+	if err != nil {
+		return nil, err
+	}
+	return translateEvents(provider, m.Spec.Events)
+}
+
+func translateEvents(provider Provider, events []string) ([]string, error) {
+	canonicalEvents := provider.CanonicalEvents()
+	nativeEvents := []string{}
+	seen := map[string]bool{}
+	for _, event := range events {
+		translated := []string{event}
+		if isCanonicalEvent(event) {
+			var supported bool
+			translated, supported = canonicalEvents[event]
+			if !supported {
+				return nil, errors.New("event " + event + " is not supported by " + provider.Name())
+			}
+		}
+		for _, nativeEvent := range translated {
+			if !seen[nativeEvent] {
+				seen[nativeEvent] = true
+				nativeEvents = append(nativeEvents, nativeEvent)
+			}
+		}
+	}
+	return nativeEvents, nil
+}
+
+// GetReferencedSecretNames returns the names of all the secrets that the GitWebhook depends on
+func (m *GitWebhook) GetReferencedSecretNames() []string {
+	names := []string{}
+	if name := m.GetWebhookSecretName(); name != "" {
+		names = append(names, name)
+	}
+	if m.Spec.WebhookURLSecret.Name != "" {
+		names = append(names, m.Spec.WebhookURLSecret.Name)
+	}
+	provider, err := m.GetProvider()
+	if err != nil {
+		return names
+	}
+	serverConfig := provider.ServerConfig(&m.Spec)
+	names = append(names, serverConfig.GetGitServerCredentials().Name)
+	if referencer, ok := serverConfig.(AdditionalSecretsReferencer); ok {
+		for _, secret := range referencer.GetAdditionalSecrets() {
+			names = append(names, secret.Name)
+		}
+	}
+	return names
+}
+
+// CustomServerConfig returns the custom spec block when it targets the provider with the given name, for the providers that are not built in
+func (s *GitWebhookSpec) CustomServerConfig(name string) ServerConfig {
+	if s.Custom == nil || s.Custom.Provider != name {
+		return nil
+	}
+	return s.Custom
+}
+
+func (c *GitHubServerConfig) GetAPIServerURL() string {
+	return c.GitHubAPIServerURL
+}
+
+func (c *GitHubServerConfig) GetGitServerCredentials() corev1.LocalObjectReference {
+	return c.GitServerCredentials
+}
+
+func (c *GitLabServerConfig) GetAPIServerURL() string {
+	return c.GitLabAPIServerURL
+}
+
+func (c *GitLabServerConfig) GetGitServerCredentials() corev1.LocalObjectReference {
+	return c.GitServerCredentials
+}
+
+func (c *GitLabServerConfig) GetAdditionalSecrets() []corev1.LocalObjectReference {
+	if c.URLVariablesSecret.Name == "" {
+		return nil
+	}
+	return []corev1.LocalObjectReference{c.URLVariablesSecret}
+}
+
+func (c *BitbucketServerConfig) GetAPIServerURL() string {
+	return c.BitbucketAPIServerURL
+}
+
+func (c *BitbucketServerConfig) GetGitServerCredentials() corev1.LocalObjectReference {
+	return c.GitServerCredentials
+}
+
+func (c *BitbucketDataCenterServerConfig) GetAPIServerURL() string {
+	return c.BitbucketDataCenterAPIServerURL
+}
+
+func (c *BitbucketDataCenterServerConfig) GetGitServerCredentials() corev1.LocalObjectReference {
+	return c.GitServerCredentials
+}
+
+func (c *GiteaServerConfig) GetAPIServerURL() string {
+	return c.GiteaAPIServerURL
+}
+
+func (c *GiteaServerConfig) GetGitServerCredentials() corev1.LocalObjectReference {
+	return c.GitServerCredentials
+}
+
+func (c *GiteaServerConfig) GetAdditionalSecrets() []corev1.LocalObjectReference {
+	if c.AuthorizationHeaderSecret.Name == "" {
+		return nil
+	}
+	return []corev1.LocalObjectReference{c.AuthorizationHeaderSecret}
+}
+
+func (c *GiteaServerConfig) ValidateUpdate(old ServerConfig) error {
+	if c.Type != old.(*GiteaServerConfig).Type {
+		return errors.New("gitea webhook type cannot be changed")
+	}
+	return nil
+}
+
+func (c *AzureDevOpsServerConfig) GetAPIServerURL() string {
+	return c.AzureDevOpsAPIServerURL
+}
+
+func (c *AzureDevOpsServerConfig) GetGitServerCredentials() corev1.LocalObjectReference {
+	return c.GitServerCredentials
+}
+
+func (c *AzureDevOpsServerConfig) ValidateUpdate(old ServerConfig) error {
+	if c.Organization != old.(*AzureDevOpsServerConfig).Organization {
+		return errors.New("azure devops organization cannot be changed")
+	}
+	return nil
+}
+
+func (c *GerritServerConfig) GetAPIServerURL() string {
+	return c.GerritAPIServerURL
+}
+
+func (c *GerritServerConfig) GetGitServerCredentials() corev1.LocalObjectReference {
+	return c.GitServerCredentials
+}
+
+func (c *GerritServerConfig) ValidateUpdate(old ServerConfig) error {
+	if c.RemoteName != old.(*GerritServerConfig).RemoteName {
+		return errors.New("gerrit remote name cannot be changed")
+	}
+	return nil
+}
+
+func (c *CustomServerConfig) GetAPIServerURL() string {
+	return c.APIServerURL
+}
+
+func (c *CustomServerConfig) GetGitServerCredentials() corev1.LocalObjectReference {
+	return c.GitServerCredentials
+}
+
+func (c *CustomServerConfig) GetAdditionalSecrets() []corev1.LocalObjectReference {
+	return c.AdditionalSecrets
+}
@@ -0,0 +1,181 @@
+package v1alpha1
+
+import (
+	"reflect"
+	"testing"
+)
+
+// eventsProvider is a provider stub that only describes events
+type eventsProvider struct {
+	Provider
+	canonicalEvents map[string][]string
+}
+
+func (p *eventsProvider) Name() string {
+	return "stub"
+}
+
+func (p *eventsProvider) CanonicalEvents() map[string][]string {
+	return p.canonicalEvents
+}
+
+func TestTranslateEvents(t *testing.T) {
+	provider := &eventsProvider{canonicalEvents: map[string][]string{
+		"push":    {"push"},
+		"tag":     {"create"},
+		"comment": {"issue_comment", "commit_comment"},
+	}}
+	tests := []struct {
+		name    string
+		events  []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "no events", events: nil, want: []string{}},
+		{name: "canonical event", events: []string{"tag"}, want: []string{"create"}},
+		{name: "canonical event with several native events", events: []string{"comment"}, want: []string{"issue_comment", "commit_comment"}},
+		{name: "native event passed through", events: []string{"workflow_job"}, want: []string{"workflow_job"}},
+		{name: "duplicates removed in order", events: []string{"push", "comment", "issue_comment", "push"}, want: []string{"push", "issue_comment", "commit_comment"}},
+		{name: "unsupported canonical event", events: []string{"push", "wiki"}, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := translateEvents(provider, test.events)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("translateEvents() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if !test.wantErr && !reflect.DeepEqual(got, test.want) {
+				t.Errorf("translateEvents() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+// serverProvider is a provider stub that is selected by one of the spec blocks
+type serverProvider struct {
+	Provider
+	name         string
+	serverConfig func(spec *GitWebhookSpec) ServerConfig
+}
+
+func (p *serverProvider) Name() string {
+	return p.name
+}
+
+func (p *serverProvider) ServerConfig(spec *GitWebhookSpec) ServerConfig {
+	return p.serverConfig(spec)
+}
+
+func TestGetProvider(t *testing.T) {
+	registered := providers
+	defer func() { providers = registered }()
+	providers = []Provider{}
+	RegisterProvider(&serverProvider{name: "gitHub", serverConfig: func(spec *GitWebhookSpec) ServerConfig {
+		if spec.GitHub == nil {
+			return nil
+		}
+		return spec.GitHub
+	}})
+	RegisterProvider(&serverProvider{name: "gitLab", serverConfig: func(spec *GitWebhookSpec) ServerConfig {
+		if spec.GitLab == nil {
+			return nil
+		}
+		return spec.GitLab
+	}})
+
+	tests := []struct {
+		name    string
+		spec    GitWebhookSpec
+		want    string
+		wantErr string
+	}{
+		{name: "github", spec: GitWebhookSpec{GitHub: &GitHubServerConfig{}}, want: "gitHub"},
+		{name: "gitlab", spec: GitWebhookSpec{GitLab: &GitLabServerConfig{}}, want: "gitLab"},
+		{name: "several providers", spec: GitWebhookSpec{GitHub: &GitHubServerConfig{}, GitLab: &GitLabServerConfig{}}, wantErr: "exactly one of gitHub, gitLab must be initialized"},
+		{name: "no provider", spec: GitWebhookSpec{}, wantErr: "exactly one of gitHub, gitLab must be initialized"},
+		{name: "custom provider not registered", spec: GitWebhookSpec{Custom: &CustomServerConfig{Provider: "forge"}}, wantErr: "custom provider forge is not registered"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gitWebhook := &GitWebhook{Spec: test.spec}
+			provider, err := gitWebhook.GetProvider()
+			if test.wantErr != "" {
+				if err == nil || err.Error() != test.wantErr {
+					t.Fatalf("GetProvider() error = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetProvider() error = %v", err)
+			}
+			if provider.Name() != test.want {
+				t.Errorf("GetProvider() = %q, want %q", provider.Name(), test.want)
+			}
+		})
+	}
+}
+
+func TestRegisterProviderTwice(t *testing.T) {
+	registered := providers
+	defer func() { providers = registered }()
+	providers = []Provider{}
+	RegisterProvider(&eventsProvider{})
+	defer func() {
+		if recover() == nil {
+			t.Error("RegisterProvider() did not panic on a duplicate provider")
+		}
+	}()
+	RegisterProvider(&eventsProvider{})
+}
+
+func TestHookFinder(t *testing.T) {
+	type listedHook struct {
+		id  string
+		url string
+	}
+	tests := []struct {
+		name      string
+		policy    string
+		owned     string
+		hooks     []listedHook
+		want      string
+		wantFound bool
+		wantErr   bool
+	}{
+		{name: "no hook", hooks: []listedHook{}, wantFound: false},
+		{name: "other url", hooks: []listedHook{{"1", "https://other.example.com"}}, wantFound: false},
+		{name: "owned hook wins over an earlier hook with the url", owned: "2", hooks: []listedHook{{"1", "https://hooks.example.com"}, {"2", "https://moved.example.com"}}, want: "2", wantFound: true},
+		{name: "first hook with the url adopted", policy: "Adopt", hooks: []listedHook{{"1", "https://hooks.example.com"}, {"2", "https://hooks.example.com"}}, want: "1", wantFound: true},
+		{name: "hook with the url in conflict", policy: "FailIfExists", hooks: []listedHook{{"1", "https://hooks.example.com"}}, wantErr: true},
+		{name: "hook with the url ignored", policy: "Ignore", hooks: []listedHook{{"1", "https://hooks.example.com"}}, wantFound: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gitWebhook := &GitWebhook{
+				Spec:   GitWebhookSpec{AdoptionPolicy: test.policy},
+				Status: GitWebhookStatus{HookID: test.owned},
+			}
+			adoption := &HookAdoption{}
+			finder := NewHookFinder[listedHook](gitWebhook, adoption, "https://hooks.example.com")
+			for _, hook := range test.hooks {
+				if finder.Owned(hook, hook.id, hook.url) {
+					if !test.wantFound || hook.id != test.want {
+						t.Fatalf("Owned(%q) = true, want %q", hook.id, test.want)
+					}
+					return
+				}
+			}
+			hook, found, err := finder.Claim()
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Claim() error = %v, wantErr %v", err, test.wantErr)
+			}
+			if found != test.wantFound || hook.id != test.want {
+				t.Errorf("Claim() = %q, %v, want %q, %v", hook.id, found, test.want, test.wantFound)
+			}
+			// the owned hook is found while listing, a hook claimed afterwards is adopted
+			if adoption.AdoptedHookID() != test.want {
+				t.Errorf("adopted hook = %q, want %q", adoption.AdoptedHookID(), test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// timeout bounds the requests to the git servers, so that an unresponsive server does not block the reconciles
+const timeout = 30 * time.Second
+
+// Client is a minimal json REST client for the git servers that do not have a go client library
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	authorize  func(req *http.Request)
+	// responsePrefix is stripped from the responses before they are decoded
+	responsePrefix []byte
+}
+
+// ClientOption configures a Client
+type ClientOption func(c *Client)
+
+// WithResponsePrefix strips prefix from the responses before they are decoded, for the servers that prefix their json responses
+func WithResponsePrefix(prefix string) ClientOption {
+	return func(c *Client) {
+		c.responsePrefix = []byte(prefix)
+	}
+}
+
+// ErrorResponse is returned when the server answers with a non 2xx status code
+type ErrorResponse struct {
+	Response *http.Response
+	Body     []byte
+}
+
+func (e *ErrorResponse) Error() string {
+	return fmt.Sprintf("%s %s: %d %s", e.Response.Request.Method, e.Response.Request.URL.Redacted(), e.Response.StatusCode, string(e.Body))
+}
+
+// NewClient creates a client, paths passed to Do are resolved relative to baseURL
+func NewClient(baseURL string, authorize func(req *http.Request), options ...ClientOption) (*Client, error) {
+	if !strings.HasSuffix(baseURL, "/") {
+		baseURL = baseURL + "/"
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{
+		baseURL:    u,
+		httpClient: &http.Client{Timeout: timeout},
+		authorize:  authorize,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c, nil
+}
+
+func BasicAuth(username string, password string) func(req *http.Request) {
+	return func(req *http.Request) {
+		req.SetBasicAuth(username, password)
+	}
+}
+
+func BearerToken(token string) func(req *http.Request) {
+	return func(req *http.Request) {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+// Do sends the request, body is encoded as json when not nil and the response is decoded in out when not nil.
+// path can be relative to the base url or absolute, as returned by servers in pagination links, absolute urls must be on the server of the base url so that the credentials are not sent elsewhere.
+func (c *Client) Do(ctx context.Context, method string, path string, body interface{}, out interface{}) (*http.Response, error) {
+	u, err := c.baseURL.Parse(path)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != c.baseURL.Scheme || u.Host != c.baseURL.Host {
+		return nil, fmt.Errorf("refusing to send a request to %s, which is not on the server %s", u.Redacted(), c.baseURL.Redacted())
+	}
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.authorize != nil {
+		c.authorize(req)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp, &ErrorResponse{
+			Response: resp,
+			Body:     data,
+		}
+	}
+	data = bytes.TrimPrefix(data, c.responsePrefix)
+	if out != nil && len(bytes.TrimSpace(data)) > 0 {
+		err = json.Unmarshal(data, out)
+		if err != nil {
+			return resp, err
+		}
+	}
+	return resp, nil
+}
+
+func IsNotFound(err error) bool {
+	errorResponse, ok := err.(*ErrorResponse)
+	return ok && errorResponse.Response.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,52 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDo(t *testing.T) {
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		w.Write([]byte(")]}'\n{\"name\": \"hook\"}"))
+	}))
+	defer server.Close()
+	tests := []struct {
+		name     string
+		path     string
+		options  []ClientOption
+		wantErr  bool
+		wantName string
+	}{
+		{name: "relative path", path: "hooks", options: []ClientOption{WithResponsePrefix(")]}'")}, wantName: "hook"},
+		{name: "pagination link on the server", path: server.URL + "/hooks?page=2", options: []ClientOption{WithResponsePrefix(")]}'")}, wantName: "hook"},
+		{name: "pagination link on another server", path: "http://other.example.com/hooks?page=2", wantErr: true},
+		{name: "pagination link with another scheme", path: "https://" + server.Listener.Addr().String() + "/hooks?page=2", wantErr: true},
+		{name: "prefix only stripped when configured", path: "hooks", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			authorization = ""
+			client, err := NewClient(server.URL, BearerToken("token"), test.options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out := struct {
+				Name string `json:"name"`
+			}{}
+			_, err = client.Do(context.TODO(), http.MethodGet, test.path, nil, &out)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Do() error = %v, want error %v", err, test.wantErr)
+			}
+			if out.Name != test.wantName {
+				t.Errorf("name = %q, want %q", out.Name, test.wantName)
+			}
+			if test.wantName != "" && authorization != "Bearer token" {
+				t.Errorf("authorization = %q, want the token", authorization)
+			}
+		})
+	}
+}
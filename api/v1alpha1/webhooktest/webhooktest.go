@@ -0,0 +1,97 @@
+// Package webhooktest provides a fake git server and a lifecycle test of the webhooks, for the tests of the providers
+package webhooktest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1"
+)
+
+// Server is a fake git server, it serves the requests with the routes of a provider and records the requests that change the webhooks
+type Server struct {
+	*httptest.Server
+	lock     sync.Mutex
+	requests []string
+}
+
+// NewServer starts a fake git server that is closed at the end of the test. The handler routes the requests of the provider,
+// it is called with the server locked so that it can change the webhooks that it serves
+func NewServer(t *testing.T, handler http.HandlerFunc) *Server {
+	s := &Server{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		if r.Method != http.MethodGet {
+			s.requests = append(s.requests, r.Method+" "+r.URL.EscapedPath())
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Mutations returns, sorted, the requests other than the reads received since the last call, as "<method> <escaped path>"
+func (s *Server) Mutations() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	mutations := append([]string{}, s.requests...)
+	sort.Strings(mutations)
+	s.requests = nil
+	return mutations
+}
+
+// UnexpectedRequest fails the test on a request that the provider is not expected to send
+func UnexpectedRequest(t *testing.T, w http.ResponseWriter, r *http.Request) {
+	t.Errorf("unexpected request %s %s", r.Method, r.URL)
+	w.WriteHeader(http.StatusNotFound)
+}
+
+// Step is a reconcile, or a delete, of the webhook in a lifecycle test
+type Step struct {
+	// Name of the step, for example "create"
+	Name string
+	// Change is applied to the resource of the webhook before the step, when not nil
+	Change func()
+	// Delete the webhook rather than reconcile it
+	Delete bool
+	// Error whether the step is expected to fail
+	Error bool
+	// Mutations the requests expected to change the webhooks on the git server, as returned by Server.Mutations
+	Mutations []string
+	// Check checks the outcome of the step, when not nil
+	Check func(t *testing.T, webHook redhatcopv1alpha1.WebHook)
+}
+
+// RunLifecycle runs the steps in order, each with a new webhook so that nothing is cached between the steps, like between the reconciles of the controller
+func RunLifecycle(t *testing.T, server *Server, newWebHook func() redhatcopv1alpha1.WebHook, steps ...Step) {
+	ctx := context.TODO()
+	for _, step := range steps {
+		if step.Change != nil {
+			step.Change()
+		}
+		webHook := newWebHook()
+		var err error
+		if step.Delete {
+			err = webHook.Delete(ctx)
+		} else {
+			err = webHook.Reconcile(ctx)
+		}
+		if (err != nil) != step.Error {
+			t.Fatalf("%s: error = %v, want error %v", step.Name, err, step.Error)
+		}
+		want := append([]string{}, step.Mutations...)
+		sort.Strings(want)
+		if got := server.Mutations(); !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: requests = %v, want %v", step.Name, got, want)
+		}
+		if step.Check != nil {
+			step.Check(t, webHook)
+		}
+	}
+}
@@ -22,10 +22,247 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AppliedWebhookSettings) DeepCopyInto(out *AppliedWebhookSettings) {
+	*out = *in
+	if in.Active != nil {
+		in, out := &in.Active, &out.Active
+		*out = new(bool)
+		**out = **in
+	}
+	if in.InsecureSSL != nil {
+		in, out := &in.InsecureSSL, &out.InsecureSSL
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AppliedWebhookSettings.
+func (in *AppliedWebhookSettings) DeepCopy() *AppliedWebhookSettings {
+	if in == nil {
+		return nil
+	}
+	out := new(AppliedWebhookSettings)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AzureDevOpsServerConfig) DeepCopyInto(out *AzureDevOpsServerConfig) {
+	*out = *in
+	out.GitServerCredentials = in.GitServerCredentials
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AzureDevOpsServerConfig.
+func (in *AzureDevOpsServerConfig) DeepCopy() *AzureDevOpsServerConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(AzureDevOpsServerConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BitbucketDataCenterServerConfig) DeepCopyInto(out *BitbucketDataCenterServerConfig) {
+	*out = *in
+	out.GitServerCredentials = in.GitServerCredentials
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BitbucketDataCenterServerConfig.
+func (in *BitbucketDataCenterServerConfig) DeepCopy() *BitbucketDataCenterServerConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(BitbucketDataCenterServerConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BitbucketServerConfig) DeepCopyInto(out *BitbucketServerConfig) {
+	*out = *in
+	out.GitServerCredentials = in.GitServerCredentials
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BitbucketServerConfig.
+func (in *BitbucketServerConfig) DeepCopy() *BitbucketServerConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(BitbucketServerConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CustomServerConfig) DeepCopyInto(out *CustomServerConfig) {
+	*out = *in
+	out.GitServerCredentials = in.GitServerCredentials
+	if in.AdditionalSecrets != nil {
+		in, out := &in.AdditionalSecrets, &out.AdditionalSecrets
+		*out = make([]corev1.LocalObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CustomServerConfig.
+func (in *CustomServerConfig) DeepCopy() *CustomServerConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(CustomServerConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GerritServerConfig) DeepCopyInto(out *GerritServerConfig) {
+	*out = *in
+	out.GitServerCredentials = in.GitServerCredentials
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GerritServerConfig.
+func (in *GerritServerConfig) DeepCopy() *GerritServerConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(GerritServerConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitHubGlobalHook) DeepCopyInto(out *GitHubGlobalHook) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitHubGlobalHook.
+func (in *GitHubGlobalHook) DeepCopy() *GitHubGlobalHook {
+	if in == nil {
+		return nil
+	}
+	out := new(GitHubGlobalHook)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GitHubGlobalHook) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitHubGlobalHookList) DeepCopyInto(out *GitHubGlobalHookList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]GitHubGlobalHook, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitHubGlobalHookList.
+func (in *GitHubGlobalHookList) DeepCopy() *GitHubGlobalHookList {
+	if in == nil {
+		return nil
+	}
+	out := new(GitHubGlobalHookList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GitHubGlobalHookList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitHubGlobalHookSpec) DeepCopyInto(out *GitHubGlobalHookSpec) {
+	*out = *in
+	out.GitHub = in.GitHub
+	out.WebhookSecret = in.WebhookSecret
+	if in.Events != nil {
+		in, out := &in.Events, &out.Events
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitHubGlobalHookSpec.
+func (in *GitHubGlobalHookSpec) DeepCopy() *GitHubGlobalHookSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GitHubGlobalHookSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitHubGlobalHookStatus) DeepCopyInto(out *GitHubGlobalHookStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitHubGlobalHookStatus.
+func (in *GitHubGlobalHookStatus) DeepCopy() *GitHubGlobalHookStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GitHubGlobalHookStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitHubInstanceServerConfig) DeepCopyInto(out *GitHubInstanceServerConfig) {
+	*out = *in
+	out.GitServerCredentials = in.GitServerCredentials
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitHubInstanceServerConfig.
+func (in *GitHubInstanceServerConfig) DeepCopy() *GitHubInstanceServerConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(GitHubInstanceServerConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GitHubServerConfig) DeepCopyInto(out *GitHubServerConfig) {
 	*out = *in
@@ -42,10 +279,47 @@ func (in *GitHubServerConfig) DeepCopy() *GitHubServerConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitLabCustomHeader) DeepCopyInto(out *GitLabCustomHeader) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitLabCustomHeader.
+func (in *GitLabCustomHeader) DeepCopy() *GitLabCustomHeader {
+	if in == nil {
+		return nil
+	}
+	out := new(GitLabCustomHeader)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitLabInstanceServerConfig) DeepCopyInto(out *GitLabInstanceServerConfig) {
+	*out = *in
+	out.GitServerCredentials = in.GitServerCredentials
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitLabInstanceServerConfig.
+func (in *GitLabInstanceServerConfig) DeepCopy() *GitLabInstanceServerConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(GitLabInstanceServerConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GitLabServerConfig) DeepCopyInto(out *GitLabServerConfig) {
 	*out = *in
 	out.GitServerCredentials = in.GitServerCredentials
+	if in.CustomHeaders != nil {
+		in, out := &in.CustomHeaders, &out.CustomHeaders
+		*out = make([]GitLabCustomHeader, len(*in))
+		copy(*out, *in)
+	}
+	out.URLVariablesSecret = in.URLVariablesSecret
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitLabServerConfig.
@@ -58,6 +332,109 @@ func (in *GitLabServerConfig) DeepCopy() *GitLabServerConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitLabSystemHook) DeepCopyInto(out *GitLabSystemHook) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitLabSystemHook.
+func (in *GitLabSystemHook) DeepCopy() *GitLabSystemHook {
+	if in == nil {
+		return nil
+	}
+	out := new(GitLabSystemHook)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GitLabSystemHook) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitLabSystemHookList) DeepCopyInto(out *GitLabSystemHookList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]GitLabSystemHook, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitLabSystemHookList.
+func (in *GitLabSystemHookList) DeepCopy() *GitLabSystemHookList {
+	if in == nil {
+		return nil
+	}
+	out := new(GitLabSystemHookList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GitLabSystemHookList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitLabSystemHookSpec) DeepCopyInto(out *GitLabSystemHookSpec) {
+	*out = *in
+	out.GitLab = in.GitLab
+	out.WebhookSecret = in.WebhookSecret
+	if in.Events != nil {
+		in, out := &in.Events, &out.Events
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitLabSystemHookSpec.
+func (in *GitLabSystemHookSpec) DeepCopy() *GitLabSystemHookSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GitLabSystemHookSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitLabSystemHookStatus) DeepCopyInto(out *GitLabSystemHookStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitLabSystemHookStatus.
+func (in *GitLabSystemHookStatus) DeepCopy() *GitLabSystemHookStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GitLabSystemHookStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GitWebhook) DeepCopyInto(out *GitWebhook) {
 	*out = *in
@@ -123,19 +500,55 @@ func (in *GitWebhookSpec) DeepCopyInto(out *GitWebhookSpec) {
 	if in.GitLab != nil {
 		in, out := &in.GitLab, &out.GitLab
 		*out = new(GitLabServerConfig)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	if in.GitHub != nil {
 		in, out := &in.GitHub, &out.GitHub
 		*out = new(GitHubServerConfig)
 		**out = **in
 	}
-	out.WebhookSecret = in.WebhookSecret
+	if in.Bitbucket != nil {
+		in, out := &in.Bitbucket, &out.Bitbucket
+		*out = new(BitbucketServerConfig)
+		**out = **in
+	}
+	if in.BitbucketDataCenter != nil {
+		in, out := &in.BitbucketDataCenter, &out.BitbucketDataCenter
+		*out = new(BitbucketDataCenterServerConfig)
+		**out = **in
+	}
+	if in.Gitea != nil {
+		in, out := &in.Gitea, &out.Gitea
+		*out = new(GiteaServerConfig)
+		**out = **in
+	}
+	if in.AzureDevOps != nil {
+		in, out := &in.AzureDevOps, &out.AzureDevOps
+		*out = new(AzureDevOpsServerConfig)
+		**out = **in
+	}
+	if in.Gerrit != nil {
+		in, out := &in.Gerrit, &out.Gerrit
+		*out = new(GerritServerConfig)
+		**out = **in
+	}
+	if in.Custom != nil {
+		in, out := &in.Custom, &out.Custom
+		*out = new(CustomServerConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	out.WebhookURLSecret = in.WebhookURLSecret
+	in.WebhookSecret.DeepCopyInto(&out.WebhookSecret)
 	if in.Events != nil {
 		in, out := &in.Events, &out.Events
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.ResyncInterval != nil {
+		in, out := &in.ResyncInterval, &out.ResyncInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitWebhookSpec.
@@ -151,6 +564,37 @@ func (in *GitWebhookSpec) DeepCopy() *GitWebhookSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GitWebhookStatus) DeepCopyInto(out *GitWebhookStatus) {
 	*out = *in
+	if in.LastSyncTime != nil {
+		in, out := &in.LastSyncTime, &out.LastSyncTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Events != nil {
+		in, out := &in.Events, &out.Events
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Settings != nil {
+		in, out := &in.Settings, &out.Settings
+		*out = new(AppliedWebhookSettings)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LastDelivery != nil {
+		in, out := &in.LastDelivery, &out.LastDelivery
+		*out = new(WebhookDelivery)
+		**out = **in
+	}
+	if in.LastSecretRotationTime != nil {
+		in, out := &in.LastSecretRotationTime, &out.LastSecretRotationTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastDriftDetectedTime != nil {
+		in, out := &in.LastDriftDetectedTime, &out.LastDriftDetectedTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastDriftCorrectedTime != nil {
+		in, out := &in.LastDriftCorrectedTime, &out.LastDriftCorrectedTime
+		*out = (*in).DeepCopy()
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]v1.Condition, len(*in))
@@ -169,3 +613,76 @@ func (in *GitWebhookStatus) DeepCopy() *GitWebhookStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GiteaServerConfig) DeepCopyInto(out *GiteaServerConfig) {
+	*out = *in
+	out.GitServerCredentials = in.GitServerCredentials
+	out.AuthorizationHeaderSecret = in.AuthorizationHeaderSecret
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GiteaServerConfig.
+func (in *GiteaServerConfig) DeepCopy() *GiteaServerConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(GiteaServerConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebhookDelivery) DeepCopyInto(out *WebhookDelivery) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebhookDelivery.
+func (in *WebhookDelivery) DeepCopy() *WebhookDelivery {
+	if in == nil {
+		return nil
+	}
+	out := new(WebhookDelivery)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebhookSecretReference) DeepCopyInto(out *WebhookSecretReference) {
+	*out = *in
+	if in.Rotation != nil {
+		in, out := &in.Rotation, &out.Rotation
+		*out = new(WebhookSecretRotation)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebhookSecretReference.
+func (in *WebhookSecretReference) DeepCopy() *WebhookSecretReference {
+	if in == nil {
+		return nil
+	}
+	out := new(WebhookSecretReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebhookSecretRotation) DeepCopyInto(out *WebhookSecretRotation) {
+	*out = *in
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	out.GracePeriod = in.GracePeriod
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebhookSecretRotation.
+func (in *WebhookSecretRotation) DeepCopy() *WebhookSecretRotation {
+	if in == nil {
+		return nil
+	}
+	out := new(WebhookSecretRotation)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,154 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	redhatcopv1alpha1 "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// clusterHook is implemented by the cluster scoped resources that manage an instance wide webhook, the GitHubGlobalHooks and the GitLabSystemHooks
+type clusterHook interface {
+	conditionsAware
+	GetReferencedSecrets() []corev1.SecretReference
+	GetHookID() string
+}
+
+// reconcileClusterHook makes sure that the webhook described by a cluster scoped resource exists on the git server, and deletes it with the resource
+func reconcileClusterHook(ctx context.Context, c client.Client, recorder record.EventRecorder, req ctrl.Request, instance clusterHook, newWebHook func() redhatcopv1alpha1.WebHook) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+	ctx = context.WithValue(ctx, "kubeClient", c)
+
+	err := c.Get(ctx, req.NamespacedName, instance)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			// Request object not found, could have been deleted after reconcile request.
+			// Return and don't requeue
+			return reconcile.Result{}, nil
+		}
+		// Error reading the object - requeue the request.
+		log.Error(err, "unable to retrieve instance")
+		return reconcile.Result{}, err
+	}
+
+	log.V(1).Info("reconcile started", "instance", instance)
+
+	if instance.GetDeletionTimestamp().IsZero() {
+		if !controllerutil.ContainsFinalizer(instance, finalizerName) {
+			controllerutil.AddFinalizer(instance, finalizerName)
+			if err := c.Update(ctx, instance); err != nil {
+				log.Error(err, "unable to add finalizer")
+				return ctrl.Result{}, err
+			}
+			return ctrl.Result{}, err
+		}
+	} else {
+		if controllerutil.ContainsFinalizer(instance, finalizerName) {
+			if err := newWebHook().Delete(ctx); err != nil {
+				log.Error(err, "unable to delete webhook")
+				return ctrl.Result{}, err
+			}
+			controllerutil.RemoveFinalizer(instance, finalizerName)
+			if err := c.Update(ctx, instance); err != nil {
+				log.Error(err, "unable to remove finalizer")
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{}, err
+	}
+	err = manageReconciling(ctx, c, instance)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	hookID := instance.GetHookID()
+	webHook := newWebHook()
+	err = webHook.Reconcile(ctx)
+	if instance.GetHookID() != hookID {
+		if err := saveHookID(ctx, c, instance, instance.GetHookID()); err != nil {
+			return manageFailure(ctx, c, recorder, instance, err)
+		}
+	}
+	if adopter, ok := webHook.(redhatcopv1alpha1.HookAdopter); ok && adopter.AdoptedHookID() != "" {
+		recorder.Event(instance, "Warning", "WebhookAdopted", "adopted the existing webhook "+adopter.AdoptedHookID()+" with the url of the resource, as per the Adopt adoption policy")
+	}
+	if err != nil {
+		return manageFailure(ctx, c, recorder, instance, err)
+	}
+	return manageSuccess(ctx, c, instance)
+}
+
+// enqueForReferencingClusterHooks enqueues the cluster scoped hooks that reference a secret, so that secret changes are pushed to the git server
+type enqueForReferencingClusterHooks struct {
+	client  client.Client
+	log     logr.Logger
+	newList func() client.ObjectList
+}
+
+func (e *enqueForReferencingClusterHooks) matchesSecret(instance clusterHook, secret *corev1.Secret) bool {
+	for _, reference := range instance.GetReferencedSecrets() {
+		if reference.Name == secret.Name && reference.Namespace == secret.Namespace {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *enqueForReferencingClusterHooks) dispatchEvents(secret *corev1.Secret, q workqueue.RateLimitingInterface) {
+	list := e.newList()
+	err := e.client.List(context.TODO(), list)
+	if err != nil {
+		e.log.Error(err, "unable to retrieve list of cluster hooks")
+		return
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		e.log.Error(err, "unable to extract list of cluster hooks")
+		return
+	}
+	for _, item := range items {
+		instance, ok := item.(clusterHook)
+		if ok && e.matchesSecret(instance, secret) {
+			q.Add(reconcile.Request{NamespacedName: types.NamespacedName{
+				Name: instance.GetName(),
+			}})
+		}
+	}
+}
+
+// Create implements EventHandler
+func (e *enqueForReferencingClusterHooks) Create(evt event.CreateEvent, q workqueue.RateLimitingInterface) {
+	secret, ok := evt.Object.(*corev1.Secret)
+	if !ok {
+		e.log.Info("unable convert event object to secret,", "event", evt)
+		return
+	}
+	e.dispatchEvents(secret, q)
+}
+
+// Update implements EventHandler
+func (e *enqueForReferencingClusterHooks) Update(evt event.UpdateEvent, q workqueue.RateLimitingInterface) {
+	secret, ok := evt.ObjectNew.(*corev1.Secret)
+	if !ok {
+		e.log.Info("unable convert event object to secret,", "event", evt)
+		return
+	}
+	e.dispatchEvents(secret, q)
+}
+
+// Delete implements EventHandler
+func (e *enqueForReferencingClusterHooks) Delete(evt event.DeleteEvent, q workqueue.RateLimitingInterface) {
+}
+func (e *enqueForReferencingClusterHooks) Generic(evt event.GenericEvent, q workqueue.RateLimitingInterface) {
+}
@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/go-logr/logr"
+	redhatcopv1alpha1 "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestEnqueForReferencingClusterHooks(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := redhatcopv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	systemHook := &redhatcopv1alpha1.GitLabSystemHook{
+		ObjectMeta: metav1.ObjectMeta{Name: "system-hook"},
+		Spec: redhatcopv1alpha1.GitLabSystemHookSpec{
+			WebhookSecret: corev1.SecretReference{Name: "webhook-secret", Namespace: "hooks"},
+			GitLab: redhatcopv1alpha1.GitLabInstanceServerConfig{
+				GitServerCredentials: corev1.SecretReference{Name: "credentials", Namespace: "hooks"},
+			},
+		},
+	}
+	handler := &enqueForReferencingClusterHooks{
+		client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(systemHook).Build(),
+		log:    logr.Discard(),
+		newList: func() client.ObjectList {
+			return &redhatcopv1alpha1.GitLabSystemHookList{}
+		},
+	}
+	tests := []struct {
+		name      string
+		secret    string
+		namespace string
+		want      bool
+	}{
+		{"webhook secret", "webhook-secret", "hooks", true},
+		{"credentials", "credentials", "hooks", true},
+		{"same name in another namespace", "webhook-secret", "default", false},
+		{"unrelated secret", "other", "hooks", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			q := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+			defer q.ShutDown()
+			handler.dispatchEvents(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: test.secret, Namespace: test.namespace}}, q)
+			if got := q.Len() == 1; got != test.want {
+				t.Fatalf("enqueued = %v, want %v", got, test.want)
+			}
+			if test.want {
+				item, _ := q.Get()
+				if item.(reconcile.Request).Name != "system-hook" {
+					t.Errorf("enqueued %v, want system-hook", item)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// the condition types follow the kubernetes api conventions, Ready is the condition that kubectl wait and the gitops health checks look at
+const (
+	conditionReady       = "Ready"
+	conditionReconciling = "Reconciling"
+	conditionDegraded    = "Degraded"
+	// conditionDeliveryHealthy reports the outcome of the last delivery of the webhook, for the git servers that report it
+	conditionDeliveryHealthy = "DeliveryHealthy"
+)
+
+const (
+	reasonReconciled      = "Reconciled"
+	reasonProgressing     = "Progressing"
+	reasonRetrying        = "Retrying"
+	reasonHookConflict    = "HookConflict"
+	reasonSecretNotFound  = "SecretNotFound"
+	reasonReconcileFailed = "ReconcileFailed"
+	reasonDelivered       = "Delivered"
+	reasonNoDelivery      = "NoDelivery"
+	reasonDeliveryFailed  = "DeliveryFailed"
+)
+
+// legacyConditionTypes are the Success and Failure condition types that the Ready and Degraded conditions replace, they are removed on the next status update
+var legacyConditionTypes = []string{"Success", "Failure"}
+
+// conditionsAware is implemented by the resources whose reconcile outcome is reported in status conditions
+type conditionsAware interface {
+	client.Object
+	GetConditions() []metav1.Condition
+	SetConditions(conditions []metav1.Condition)
+}
+
+// manageReconciling flags the resource as being reconciled when its spec changed since the last reconcile, so that the Ready condition of the previous spec is not mistaken for the outcome of the new one
+func manageReconciling(ctx context.Context, c client.Client, instance conditionsAware) error {
+	log := log.FromContext(ctx)
+	ready := meta.FindStatusCondition(instance.GetConditions(), conditionReady)
+	if ready != nil && ready.ObservedGeneration == instance.GetGeneration() {
+		return nil
+	}
+	setConditions(instance,
+		newCondition(instance, conditionReady, metav1.ConditionUnknown, reasonProgressing, "the webhook is being reconciled"),
+		newCondition(instance, conditionReconciling, metav1.ConditionTrue, reasonProgressing, "the webhook is being reconciled"),
+	)
+	err := c.Status().Update(ctx, instance)
+	if err != nil {
+		log.Error(err, "unable to update status")
+		return err
+	}
+	return nil
+}
+
+func manageSuccess(ctx context.Context, c client.Client, instance conditionsAware) (reconcile.Result, error) {
+	log := log.FromContext(ctx)
+	setConditions(instance,
+		newCondition(instance, conditionReady, metav1.ConditionTrue, reasonReconciled, "the webhook is in sync with the git server"),
+		newCondition(instance, conditionReconciling, metav1.ConditionFalse, reasonReconciled, ""),
+		newCondition(instance, conditionDegraded, metav1.ConditionFalse, reasonReconciled, ""),
+	)
+	err := c.Status().Update(ctx, instance)
+	if err != nil {
+		log.Error(err, "unable to update status")
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{}, nil
+}
+
+// manageFailure reports the error in the Ready and Degraded conditions, the reconcile is retried with a backoff as the error is returned
+func manageFailure(context context.Context, c client.Client, recorder record.EventRecorder, instance conditionsAware, issue error) (reconcile.Result, error) {
+	log := log.FromContext(context)
+	recorder.Event(instance, "Warning", "ProcessingError", issue.Error())
+
+	reason := failureReason(issue)
+	setConditions(instance,
+		newCondition(instance, conditionReady, metav1.ConditionFalse, reason, issue.Error()),
+		newCondition(instance, conditionReconciling, metav1.ConditionTrue, reasonRetrying, "the reconcile is retried with a backoff"),
+		newCondition(instance, conditionDegraded, metav1.ConditionTrue, reason, issue.Error()),
+	)
+	err := c.Status().Update(context, instance)
+	if err != nil {
+		log.Error(err, "unable to update status")
+		return reconcile.Result{}, err
+	}
+
+	return reconcile.Result{}, issue
+}
+
+func failureReason(issue error) string {
+	var conflict *redhatcopv1alpha1.HookConflictError
+	var secretNotFound *redhatcopv1alpha1.SecretNotFoundError
+	switch {
+	case errors.As(issue, &conflict):
+		return reasonHookConflict
+	case errors.As(issue, &secretNotFound):
+		return reasonSecretNotFound
+	default:
+		return reasonReconcileFailed
+	}
+}
+
+func newCondition(instance conditionsAware, conditionType string, status metav1.ConditionStatus, reason string, message string) metav1.Condition {
+	return metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		ObservedGeneration: instance.GetGeneration(),
+		Reason:             reason,
+		Message:            message,
+	}
+}
+
+// setConditions relies on meta.SetStatusCondition, which only moves the LastTransitionTime when the status of a condition changes
+func setConditions(instance conditionsAware, newConditions ...metav1.Condition) {
+	conditions := instance.GetConditions()
+	for _, conditionType := range legacyConditionTypes {
+		meta.RemoveStatusCondition(&conditions, conditionType)
+	}
+	for _, condition := range newConditions {
+		meta.SetStatusCondition(&conditions, condition)
+	}
+	instance.SetConditions(conditions)
+}
@@ -0,0 +1,34 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestFailureReason(t *testing.T) {
+	notFound := apierrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, "credentials")
+	tests := []struct {
+		name  string
+		issue error
+		want  string
+	}{
+		{"hook conflict", &redhatcopv1alpha1.HookConflictError{HookID: "1"}, reasonHookConflict},
+		{"wrapped hook conflict", fmt.Errorf("reconcile: %w", &redhatcopv1alpha1.HookConflictError{HookID: "1"}), reasonHookConflict},
+		{"secret not found", &redhatcopv1alpha1.SecretNotFoundError{Name: "credentials", Err: notFound}, reasonSecretNotFound},
+		{"wrapped secret not found", fmt.Errorf("reconcile: %w", &redhatcopv1alpha1.SecretNotFoundError{Name: "credentials", Err: notFound}), reasonSecretNotFound},
+		{"other resource not found", apierrors.NewNotFound(schema.GroupResource{Resource: "projects"}, "team/app"), reasonReconcileFailed},
+		{"other error", errors.New("connection refused"), reasonReconcileFailed},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := failureReason(test.issue); got != test.want {
+				t.Errorf("failureReason() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1"
+	"github.com/redhat-cop/gitwebhook-operator/api/v1alpha1/github"
+)
+
+// GitHubGlobalHookReconciler reconciles a GitHubGlobalHook object
+type GitHubGlobalHookReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+}
+
+//+kubebuilder:rbac:groups=redhatcop.redhat.io,resources=githubglobalhooks,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=redhatcop.redhat.io,resources=githubglobalhooks/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=redhatcop.redhat.io,resources=githubglobalhooks/finalizers,verbs=update
+
+// Reconcile makes sure that the global webhook described by a GitHubGlobalHook exists on the github enterprise server
+func (r *GitHubGlobalHookReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	instance := &redhatcopv1alpha1.GitHubGlobalHook{}
+	return reconcileClusterHook(ctx, r.Client, r.Recorder, req, instance, func() redhatcopv1alpha1.WebHook {
+		return github.FromGitHubGlobalHook(instance)
+	})
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *GitHubGlobalHookReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&redhatcopv1alpha1.GitHubGlobalHook{}, builder.WithPredicates(ExcludeManagedFieldsAndStatus{})).
+		Watches(&source.Kind{Type: &corev1.Secret{
+			TypeMeta: metav1.TypeMeta{
+				Kind: "Secret",
+			}}}, &enqueForReferencingClusterHooks{
+			log:    mgr.GetLogger().WithName("enqueForSelectedGitHubGlobalHook"),
+			client: r.Client,
+			newList: func() client.ObjectList {
+				return &redhatcopv1alpha1.GitHubGlobalHookList{}
+			},
+		}).
+		Complete(r)
+}
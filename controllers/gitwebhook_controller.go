@@ -18,16 +18,21 @@ package controllers
 
 import (
 	"context"
-	err "errors"
+	"encoding/json"
 	"reflect"
+	"strconv"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
@@ -38,8 +43,6 @@ import (
 
 	"github.com/go-logr/logr"
 	redhatcopv1alpha1 "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1"
-	"github.com/redhat-cop/gitwebhook-operator/api/v1alpha1/github"
-	"github.com/redhat-cop/gitwebhook-operator/api/v1alpha1/gitlab"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -49,6 +52,8 @@ type GitWebhookReconciler struct {
 	client.Client
 	Scheme   *runtime.Scheme
 	Recorder record.EventRecorder
+	// ResyncInterval how often the webhooks are compared with the git servers when the GitWebhooks do not set their own interval, zero disables the periodic resync
+	ResyncInterval time.Duration
 }
 
 const finalizerName = "gitwebhook.redhatcop.redhat.io/finalizer"
@@ -56,7 +61,7 @@ const finalizerName = "gitwebhook.redhatcop.redhat.io/finalizer"
 //+kubebuilder:rbac:groups=redhatcop.redhat.io,resources=gitwebhooks,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=redhatcop.redhat.io,resources=gitwebhooks/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=redhatcop.redhat.io,resources=gitwebhooks/finalizers,verbs=update
-//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update
 //+kubebuilder:rbac:groups="coordination.k8s.io",resources=leases,verbs=get;list;watch;update;patch;delete
 //+kubebuilder:rbac:groups="",resources=events,verbs=get;list;watch;create;patch
 
@@ -122,97 +127,230 @@ func (r *GitWebhookReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		// Stop reconciliation as the item is being deleted
 		return ctrl.Result{}, err
 	}
+	err = manageReconciling(ctx, r.Client, instance)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	generated, err := r.ensureWebhookSecret(ctx, instance)
+	if err != nil {
+		return manageFailure(ctx, r.Client, r.Recorder, instance, err)
+	}
+	if generated {
+		return ctrl.Result{}, nil
+	}
+	requeueAfter, err := r.rotateWebhookSecret(ctx, instance)
+	if err != nil {
+		return manageFailure(ctx, r.Client, r.Recorder, instance, err)
+	}
 	err = r.reconcileWebHook(ctx, instance)
 	if err != nil {
-		return r.manageFailure(ctx, instance, err)
+		return manageFailure(ctx, r.Client, r.Recorder, instance, err)
+	}
+	expireAfter, err := r.expirePreviousWebhookSecret(ctx, instance)
+	if err != nil {
+		return manageFailure(ctx, r.Client, r.Recorder, instance, err)
+	}
+	result, err := manageSuccess(ctx, r.Client, instance)
+	if err != nil {
+		return result, err
 	}
-	return r.manageSuccess(ctx, instance)
+	result.RequeueAfter = earliest(requeueAfter, expireAfter, r.resyncAfter(instance))
+	return result, nil
+}
+
+// resyncAfter returns after how long the webhook must be compared again with the git server, with a jitter of up to 10% so that the GitWebhooks reconciled together do not hit the git servers together again
+func (r *GitWebhookReconciler) resyncAfter(instance *redhatcopv1alpha1.GitWebhook) time.Duration {
+	interval := r.ResyncInterval
+	if instance.Spec.ResyncInterval != nil {
+		interval = instance.Spec.ResyncInterval.Duration
+	}
+	if interval <= 0 {
+		return 0
+	}
+	return wait.Jitter(interval, 0.1)
+}
+
+// earliest returns the shortest of the durations, ignoring the zero durations which mean that no requeue is needed
+func earliest(durations ...time.Duration) time.Duration {
+	var result time.Duration
+	for _, duration := range durations {
+		if duration > 0 && (result == 0 || duration < result) {
+			result = duration
+		}
+	}
+	return result
 }
 
 func (r *GitWebhookReconciler) deleteWebhook(ctx context.Context, instance *redhatcopv1alpha1.GitWebhook) error {
-	if instance.Spec.GitHub != nil {
-		return github.FromGitWebhook(instance).Delete(ctx)
+	if instance.Spec.DeletionPolicy == redhatcopv1alpha1.DeletionPolicyRetain {
+		log.FromContext(ctx).Info("retaining webhook on the git server as per deletion policy")
+		return nil
 	}
-	if instance.Spec.GitLab != nil {
-		return gitlab.FromGitWebhook(instance).Delete(ctx)
+	provider, err := instance.GetProvider()
+	if err != nil {
+		return err
 	}
-	return err.New("unable to find gitserver definition")
+	return provider.NewWebHook(instance).Delete(ctx)
 }
 
 func (r *GitWebhookReconciler) reconcileWebHook(ctx context.Context, instance *redhatcopv1alpha1.GitWebhook) error {
-	if instance.Spec.GitHub != nil {
-		return github.FromGitWebhook(instance).Reconcile(ctx)
+	provider, err := instance.GetProvider()
+	if err != nil {
+		return err
+	}
+	// the secret is read before the webhook is reconciled, so that a secret changed in the meantime is applied by the next reconcile
+	secret, err := instance.GetWebhookSecret(ctx)
+	if err != nil {
+		return err
+	}
+	webhookURL, err := instance.GetWebhookURL(ctx)
+	if err != nil {
+		return err
+	}
+	hookID := instance.Status.HookID
+	webhook := provider.NewWebHook(instance)
+	drifted, err := r.reconcileProviderWebHook(ctx, webhook, instance, secret, webhookURL)
+	if instance.Status.HookID != hookID {
+		if err := saveHookID(ctx, r.Client, instance, instance.Status.HookID); err != nil {
+			return err
+		}
+	}
+	if adopter, ok := webhook.(redhatcopv1alpha1.HookAdopter); ok && adopter.AdoptedHookID() != "" {
+		r.Recorder.Event(instance, "Warning", "WebhookAdopted", "adopted the existing webhook "+adopter.AdoptedHookID()+" with the url of the GitWebhook, as per the Adopt adoption policy")
 	}
-	if instance.Spec.GitLab != nil {
-		return gitlab.FromGitWebhook(instance).Reconcile(ctx)
+	if err != nil {
+		return err
+	}
+	if drifted {
+		instance.Status.LastDriftCorrectedTime = &metav1.Time{Time: time.Now()}
+		r.Recorder.Event(instance, "Normal", "DriftCorrected", "updated the webhook on the git server to match the GitWebhook")
 	}
-	return err.New("unable to find gitserver definition")
+	instance.SetAppliedWebhookSecret(secret)
+	// the applied url is recorded, so that the webhook can still be found after the url stored in webhookURLSecret changes
+	instance.SetAppliedWebhookURL(webhookURL)
+	r.manageDeliveryHealth(instance)
+	return recordAppliedWebHook(instance, provider, webhook)
 }
 
-// SetupWithManager sets up the controller with the Manager.
-func (r *GitWebhookReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
-		For(&redhatcopv1alpha1.GitWebhook{}).
-		WithEventFilter(ExcludeManagedFieldsAndStatus{}).
-		Watches(&source.Kind{Type: &corev1.Secret{
-			TypeMeta: metav1.TypeMeta{
-				Kind: "Secret",
-			}}}, &enqueForSelectedGitWebhook{
-			log:    mgr.GetLogger().WithName("enqueForSelectedGitWebhook"),
-			client: r.Client,
-		}).
-		Complete(r)
+// manageDeliveryHealth reports the last delivery of the webhook in the DeliveryHealthy condition, a receiver that fails is the usual reason for pipelines that do not trigger
+func (r *GitWebhookReconciler) manageDeliveryHealth(instance *redhatcopv1alpha1.GitWebhook) {
+	delivery := instance.Status.LastDelivery
+	if delivery == nil {
+		meta.RemoveStatusCondition(&instance.Status.Conditions, conditionDeliveryHealthy)
+		return
+	}
+	message := "the git server reports status " + delivery.Status
+	if delivery.Code != 0 {
+		message = "the receiver returned " + strconv.Itoa(delivery.Code)
+	}
+	if delivery.Message != "" {
+		message += ": " + delivery.Message
+	}
+	switch {
+	case delivery.Failed:
+		previous := meta.FindStatusCondition(instance.Status.Conditions, conditionDeliveryHealthy)
+		if previous == nil || previous.Status != metav1.ConditionFalse {
+			r.Recorder.Event(instance, "Warning", reasonDeliveryFailed, message)
+		}
+		setConditions(instance, newCondition(instance, conditionDeliveryHealthy, metav1.ConditionFalse, reasonDeliveryFailed, message))
+	case delivery.Code == 0:
+		setConditions(instance, newCondition(instance, conditionDeliveryHealthy, metav1.ConditionUnknown, reasonNoDelivery, "no delivery reported by the git server yet"))
+	default:
+		setConditions(instance, newCondition(instance, conditionDeliveryHealthy, metav1.ConditionTrue, reasonDelivered, message))
+	}
 }
 
-func (r *GitWebhookReconciler) manageSuccess(ctx context.Context, instance *redhatcopv1alpha1.GitWebhook) (reconcile.Result, error) {
-	log := log.FromContext(ctx)
-	condition := metav1.Condition{
-		Type:               "Success",
-		LastTransitionTime: metav1.Now(),
-		ObservedGeneration: instance.GetGeneration(),
-		Reason:             "Webhook_created_updated",
-		Status:             metav1.ConditionTrue,
-	}
-	instance.Status.Conditions = (addOrReplaceCondition(condition, instance.Status.Conditions))
-	err := r.Client.Status().Update(ctx, instance)
+// recordAppliedWebHook records in the status what was applied to the git server, so that it can be checked without access to the git server.
+// When the webhook was found in sync without being reconciled, what was last applied is left in place
+func recordAppliedWebHook(instance *redhatcopv1alpha1.GitWebhook, provider redhatcopv1alpha1.Provider, webhook redhatcopv1alpha1.WebHook) error {
+	instance.Status.ObservedGeneration = instance.GetGeneration()
+	instance.Status.LastSyncTime = &metav1.Time{Time: time.Now()}
+	instance.Status.Provider = provider.Name()
+	if reporter, ok := webhook.(redhatcopv1alpha1.AppliedWebHookReporter); ok {
+		if applied := reporter.AppliedWebHook(); applied != nil {
+			instance.Status.RepositoryID = applied.RepositoryID
+			instance.Status.Events = applied.Events
+			instance.Status.Settings = applied.Settings.DeepCopy()
+		}
+		return nil
+	}
+	events, err := instance.GetNativeEvents()
 	if err != nil {
-		log.Error(err, "unable to update status")
-		return reconcile.Result{}, err
+		return err
 	}
-	return reconcile.Result{}, nil
+	active, insecureSSL := instance.Spec.Active, instance.Spec.InsecureSSL
+	instance.Status.Events = events
+	instance.Status.Settings = &redhatcopv1alpha1.AppliedWebhookSettings{
+		ContentType:           instance.Spec.ContentType,
+		Active:                &active,
+		InsecureSSL:           &insecureSSL,
+		PushEventBranchFilter: instance.Spec.PushEventBranchFilter,
+	}
+	return nil
 }
 
-func addOrReplaceCondition(c metav1.Condition, conditions []metav1.Condition) []metav1.Condition {
-	for i, condition := range conditions {
-		if c.Type == condition.Type {
-			conditions[i] = c
-			return conditions
-		}
+// reconcileProviderWebHook reconciles the webhook on the git server. When nothing changed since the last successful reconcile, which is the case of the periodic resyncs,
+// the webhook is first compared with the git server, so that it is only updated when it drifted and the drift is recorded. It returns whether the webhook drifted
+func (r *GitWebhookReconciler) reconcileProviderWebHook(ctx context.Context, webhook redhatcopv1alpha1.WebHook, instance *redhatcopv1alpha1.GitWebhook, secret string, webhookURL string) (bool, error) {
+	driftDetector, ok := webhook.(redhatcopv1alpha1.DriftDetector)
+	if !ok || !isSynced(instance, secret, webhookURL) {
+		return false, webhook.Reconcile(ctx)
+	}
+	equivalent, err := driftDetector.IsEquivalent(ctx)
+	if err != nil {
+		return false, err
+	}
+	if equivalent {
+		return false, nil
 	}
-	conditions = append(conditions, c)
-	return conditions
+	log.FromContext(ctx).Info("webhook drifted on the git server")
+	instance.Status.LastDriftDetectedTime = &metav1.Time{Time: time.Now()}
+	r.Recorder.Event(instance, "Warning", "DriftDetected", "the webhook on the git server differs from the GitWebhook")
+	return true, webhook.Reconcile(ctx)
 }
 
-func (r *GitWebhookReconciler) manageFailure(context context.Context, instance *redhatcopv1alpha1.GitWebhook, issue error) (reconcile.Result, error) {
-	log := log.FromContext(context)
-	r.Recorder.Event(instance, "Warning", "ProcessingError", issue.Error())
+// isSynced returns whether neither the GitWebhook, nor its webhook secret, nor its webhook url changed since the last successful reconcile
+func isSynced(instance *redhatcopv1alpha1.GitWebhook, secret string, webhookURL string) bool {
+	ready := meta.FindStatusCondition(instance.Status.Conditions, conditionReady)
+	return ready != nil && ready.Status == metav1.ConditionTrue && ready.ObservedGeneration == instance.GetGeneration() &&
+		instance.IsWebhookSecretApplied(secret) && instance.IsWebhookURLApplied(webhookURL)
+}
 
-	condition := metav1.Condition{
-		Type:               "Failure",
-		LastTransitionTime: metav1.Now(),
-		ObservedGeneration: instance.GetGeneration(),
-		Message:            issue.Error(),
-		Reason:             "reconcile_failed",
-		Status:             metav1.ConditionTrue,
+// saveHookID saves the id of the webhook created or adopted right away, so that the webhook is not mistaken for a webhook not managed by the resource when the status update at the end of the reconcile fails
+func saveHookID(ctx context.Context, c client.Client, instance client.Object, hookID string) error {
+	log := log.FromContext(ctx)
+	patch, err := json.Marshal(map[string]interface{}{
+		"status": map[string]interface{}{
+			"hookID": hookID,
+		},
+	})
+	if err != nil {
+		log.Error(err, "unable to create status patch")
+		return err
 	}
-	instance.Status.Conditions = (addOrReplaceCondition(condition, instance.Status.Conditions))
-	err := r.Client.Status().Update(context, instance)
+	// the patch is applied to a copy, so that the status changes not saved yet are kept
+	saved := instance.DeepCopyObject().(client.Object)
+	err = c.Status().Patch(ctx, saved, client.RawPatch(types.MergePatchType, patch))
 	if err != nil {
-		log.Error(err, "unable to update status")
-		return reconcile.Result{}, err
+		log.Error(err, "unable to save webhook id", "hookID", hookID)
+		return err
 	}
+	instance.SetResourceVersion(saved.GetResourceVersion())
+	return nil
+}
 
-	return reconcile.Result{}, issue
+// SetupWithManager sets up the controller with the Manager.
+func (r *GitWebhookReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&redhatcopv1alpha1.GitWebhook{}, builder.WithPredicates(ExcludeManagedFieldsAndStatus{})).
+		Watches(&source.Kind{Type: &corev1.Secret{
+			TypeMeta: metav1.TypeMeta{
+				Kind: "Secret",
+			}}}, &enqueForSelectedGitWebhook{
+			log:    mgr.GetLogger().WithName("enqueForSelectedGitWebhook"),
+			client: r.Client,
+		}).
+		Complete(r)
 }
 
 type enqueForSelectedGitWebhook struct {
@@ -222,9 +360,12 @@ type enqueForSelectedGitWebhook struct {
 
 // return whether this EgressIPAM macthes this hostSubnet and with which CIDR
 func (e *enqueForSelectedGitWebhook) matchesSecret(instance *redhatcopv1alpha1.GitWebhook, secret *corev1.Secret) bool {
-	return instance.Spec.WebhookSecret.Name == secret.Name ||
-		(instance.Spec.GitHub != nil && instance.Spec.GitHub.GitServerCredentials.Name == secret.Name) ||
-		(instance.Spec.GitLab != nil && instance.Spec.GitLab.GitServerCredentials.Name == secret.Name)
+	for _, name := range instance.GetReferencedSecretNames() {
+		if name == secret.Name {
+			return true
+		}
+	}
+	return false
 }
 
 func (e *enqueForSelectedGitWebhook) getAllGitWebhooks(namespace string) ([]redhatcopv1alpha1.GitWebhook, error) {
@@ -277,7 +418,14 @@ func (e *enqueForSelectedGitWebhook) Update(evt event.UpdateEvent, q workqueue.R
 }
 
 // Delete implements EventHandler
+// trigger a reconcile for the GitWebhooks that reference this secret, so that a deleted generated secret is generated again
 func (e *enqueForSelectedGitWebhook) Delete(evt event.DeleteEvent, q workqueue.RateLimitingInterface) {
+	secret, ok := evt.Object.(*corev1.Secret)
+	if !ok {
+		e.log.Info("unable convert event object to secret,", "event", evt)
+		return
+	}
+	e.dispatchEvents(secret, q)
 }
 func (e *enqueForSelectedGitWebhook) Generic(evt event.GenericEvent, q workqueue.RateLimitingInterface) {
 }
@@ -288,22 +436,44 @@ type ExcludeManagedFieldsAndStatus struct {
 
 // Update implements default UpdateEvent filter for validating resource version change
 func (ExcludeManagedFieldsAndStatus) Update(e event.UpdateEvent) bool {
-	var old *redhatcopv1alpha1.GitWebhook
-	var new *redhatcopv1alpha1.GitWebhook
-	var ok bool
-	if old, ok = e.ObjectOld.(*redhatcopv1alpha1.GitWebhook); !ok {
+	old, ok := withoutManagedFieldsAndStatus(e.ObjectOld)
+	if !ok {
 		return false
 	}
-	if new, ok = e.ObjectNew.(*redhatcopv1alpha1.GitWebhook); !ok {
+	new, ok := withoutManagedFieldsAndStatus(e.ObjectNew)
+	if !ok {
 		return false
 	}
-	old = old.DeepCopy()
-	new = new.DeepCopy()
-	old.ObjectMeta.ManagedFields = []metav1.ManagedFieldsEntry{}
-	new.ObjectMeta.ManagedFields = []metav1.ManagedFieldsEntry{}
-	old.Status = redhatcopv1alpha1.GitWebhookStatus{}
-	new.Status = redhatcopv1alpha1.GitWebhookStatus{}
-	old.ResourceVersion = ""
-	new.ResourceVersion = ""
 	return !reflect.DeepEqual(old, new)
 }
+
+// withoutManagedFieldsAndStatus returns a copy of the object without the fields that change on every update
+func withoutManagedFieldsAndStatus(object client.Object) (client.Object, bool) {
+	switch v := object.(type) {
+	case *redhatcopv1alpha1.GitWebhook:
+		{
+			v = v.DeepCopy()
+			v.Status = redhatcopv1alpha1.GitWebhookStatus{}
+			object = v
+		}
+	case *redhatcopv1alpha1.GitLabSystemHook:
+		{
+			v = v.DeepCopy()
+			v.Status = redhatcopv1alpha1.GitLabSystemHookStatus{}
+			object = v
+		}
+	case *redhatcopv1alpha1.GitHubGlobalHook:
+		{
+			v = v.DeepCopy()
+			v.Status = redhatcopv1alpha1.GitHubGlobalHookStatus{}
+			object = v
+		}
+	default:
+		{
+			return nil, false
+		}
+	}
+	object.SetManagedFields([]metav1.ManagedFieldsEntry{})
+	object.SetResourceVersion("")
+	return object, true
+}
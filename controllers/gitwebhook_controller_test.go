@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func TestEarliest(t *testing.T) {
+	tests := []struct {
+		name      string
+		durations []time.Duration
+		want      time.Duration
+	}{
+		{name: "no durations", want: 0},
+		{name: "only zero durations", durations: []time.Duration{0, 0}, want: 0},
+		{name: "zero durations ignored", durations: []time.Duration{0, time.Hour, 0}, want: time.Hour},
+		{name: "shortest duration", durations: []time.Duration{time.Hour, time.Minute, 10 * time.Minute}, want: time.Minute},
+		{name: "negative durations ignored", durations: []time.Duration{-time.Minute, time.Hour}, want: time.Hour},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := earliest(test.durations...); got != test.want {
+				t.Errorf("earliest() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestResyncAfter(t *testing.T) {
+	tests := []struct {
+		name           string
+		defaultResync  time.Duration
+		resyncInterval *metav1.Duration
+		want           time.Duration
+	}{
+		{name: "default interval", defaultResync: time.Hour, want: time.Hour},
+		{name: "interval of the GitWebhook", defaultResync: time.Hour, resyncInterval: &metav1.Duration{Duration: 10 * time.Minute}, want: 10 * time.Minute},
+		{name: "resync disabled", defaultResync: 0, want: 0},
+		{name: "resync disabled by the GitWebhook", defaultResync: time.Hour, resyncInterval: &metav1.Duration{}, want: 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := &GitWebhookReconciler{ResyncInterval: test.defaultResync}
+			instance := &redhatcopv1alpha1.GitWebhook{Spec: redhatcopv1alpha1.GitWebhookSpec{ResyncInterval: test.resyncInterval}}
+			got := r.resyncAfter(instance)
+			// the jitter adds up to 10% to the interval
+			if got < test.want || got > test.want+test.want/10 {
+				t.Errorf("resyncAfter() = %v, want between %v and %v", got, test.want, test.want+test.want/10)
+			}
+		})
+	}
+}
+
+func TestDeletionPolicy(t *testing.T) {
+	tests := []struct {
+		name         string
+		policy       redhatcopv1alpha1.DeletionPolicy
+		wantRequests bool
+	}{
+		{name: "delete", policy: redhatcopv1alpha1.DeletionPolicyDelete, wantRequests: true},
+		{name: "retain", policy: redhatcopv1alpha1.DeletionPolicyRetain, wantRequests: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			requests := []string{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests = append(requests, r.Method+" "+r.URL.Path)
+				// the repository has no webhook left to delete
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte("[]"))
+			}))
+			defer server.Close()
+			deletionTimestamp := metav1.Now()
+			instance := &redhatcopv1alpha1.GitWebhook{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "hook",
+					Namespace:         "default",
+					Finalizers:        []string{finalizerName},
+					DeletionTimestamp: &deletionTimestamp,
+				},
+				Spec: redhatcopv1alpha1.GitWebhookSpec{
+					GitHub: &redhatcopv1alpha1.GitHubServerConfig{
+						GitHubAPIServerURL:   server.URL + "/api/v3/",
+						GitServerCredentials: corev1.LocalObjectReference{Name: "credentials"},
+					},
+					RepositoryOwner: "team",
+					RepositoryName:  "app",
+					WebhookURL:      "https://hooks.example.com/app",
+					DeletionPolicy:  test.policy,
+				},
+			}
+			credentials := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "credentials", Namespace: "default"},
+				Data:       map[string][]byte{"token": []byte("token")},
+			}
+			r := newTestReconciler(t, instance, credentials)
+			_, err := r.Reconcile(context.TODO(), ctrl.Request{NamespacedName: types.NamespacedName{Name: "hook", Namespace: "default"}})
+			if err != nil {
+				t.Fatalf("Reconcile() error = %v", err)
+			}
+			if (len(requests) > 0) != test.wantRequests {
+				t.Errorf("requests to the git server = %v, want requests %v", requests, test.wantRequests)
+			}
+			// the GitWebhook is gone once its finalizer is removed
+			deleted := &redhatcopv1alpha1.GitWebhook{}
+			err = r.Get(context.TODO(), types.NamespacedName{Name: "hook", Namespace: "default"}, deleted)
+			if !errors.IsNotFound(err) {
+				t.Errorf("GitWebhook not deleted, finalizers = %v, error = %v", deleted.Finalizers, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,264 @@
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// rotateSecretAnnotation requests the rotation of the webhook secret, the rotation happens each time the value of the annotation changes
+const rotateSecretAnnotation = "gitwebhook.redhatcop.redhat.io/rotate-secret"
+
+// allowRotationAnnotation lets the operator rotate a webhook secret that it did not generate, when set to "true" on the secret.
+// The secrets supplied by the users, for example from a gitops repository, are otherwise left untouched
+const allowRotationAnnotation = "gitwebhook.redhatcop.redhat.io/allow-rotation"
+
+// the state of the rotation is kept in the annotations of the webhook secret, which is updated together with its value
+const (
+	// rotatedAtAnnotation when the secret was last rotated
+	rotatedAtAnnotation = "gitwebhook.redhatcop.redhat.io/rotated-at"
+	// rotationRequestAnnotation the value of the rotate-secret annotation of the GitWebhook that requested the last rotation
+	rotationRequestAnnotation = "gitwebhook.redhatcop.redhat.io/rotation-request"
+	// rotationAppliedAtAnnotation when the rotated value was found applied to the git server, the grace period of the previous value starts then
+	rotationAppliedAtAnnotation = "gitwebhook.redhatcop.redhat.io/rotation-applied-at"
+)
+
+func generateSecretValue() ([]byte, error) {
+	value := make([]byte, 32)
+	_, err := rand.Read(value)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(hex.EncodeToString(value)), nil
+}
+
+// ensureWebhookSecret generates the webhook secret when it does not exist, the generated secret is owned by the GitWebhook.
+// It returns whether the secret was generated, in which case the creation event of the secret triggers the next reconcile
+func (r *GitWebhookReconciler) ensureWebhookSecret(ctx context.Context, instance *redhatcopv1alpha1.GitWebhook) (bool, error) {
+	log := log.FromContext(ctx)
+	name := instance.GetWebhookSecretName()
+	if name == "" || !instance.SupportsWebhookSecret() {
+		return false, nil
+	}
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{
+		Name:      name,
+		Namespace: instance.GetNamespace(),
+	}, secret)
+	if err == nil {
+		return false, nil
+	}
+	if !errors.IsNotFound(err) {
+		log.Error(err, "unable to retrieve webhook secret", "secret", name)
+		return false, err
+	}
+	value, err := generateSecretValue()
+	if err != nil {
+		log.Error(err, "unable to generate webhook secret")
+		return false, err
+	}
+	secret = &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: instance.GetNamespace(),
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{
+			"secret": value,
+		},
+	}
+	err = controllerutil.SetControllerReference(instance, secret, r.Scheme)
+	if err != nil {
+		log.Error(err, "unable to set owner reference on webhook secret", "secret", name)
+		return false, err
+	}
+	err = r.Create(ctx, secret)
+	if errors.IsAlreadyExists(err) {
+		// the cache is not up to date yet, the creation event of the secret triggers the next reconcile
+		return true, nil
+	}
+	if err != nil {
+		log.Error(err, "unable to create webhook secret", "secret", name)
+		return false, err
+	}
+	r.Recorder.Event(instance, "Normal", "WebhookSecretGenerated", "generated webhook secret "+name)
+	return true, nil
+}
+
+// rotateWebhookSecret replaces the value of the webhook secret when the rotation interval has elapsed or when a rotation is requested with the annotation.
+// The previous value is kept in the "previousSecret" key until the grace period has elapsed after the new value was applied to the git server, see expirePreviousWebhookSecret.
+// The time and the request of the rotation are recorded in the annotations of the secret, in the same update as the new value, so that a rotation is neither lost nor repeated when the status update fails.
+// A rotation is deferred while the previous value is kept, as the previous value may still be the one used by the git server.
+// Only the secrets generated by the operator, or annotated with allow-rotation, are rotated, a rotation due on another secret is reported with a warning event.
+// It returns after how long the secret must be checked again, zero when no rotation is scheduled.
+func (r *GitWebhookReconciler) rotateWebhookSecret(ctx context.Context, instance *redhatcopv1alpha1.GitWebhook) (time.Duration, error) {
+	log := log.FromContext(ctx)
+	rotation := instance.Spec.WebhookSecret.Rotation
+	name := instance.GetWebhookSecretName()
+	if rotation == nil || name == "" || !instance.SupportsWebhookSecret() {
+		return 0, nil
+	}
+	secret, err := r.getWebhookSecret(ctx, instance)
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	lastRotation := lastSecretRotation(secret)
+	request, requested := instance.GetAnnotations()[rotateSecretAnnotation]
+	lastRequest := secret.GetAnnotations()[rotationRequestAnnotation]
+	due := requested && request != lastRequest
+	if rotation.Interval != nil && !now.Before(lastRotation.Add(rotation.Interval.Duration)) {
+		due = true
+	}
+	if _, pending := secret.Data["previousSecret"]; pending {
+		// the previous value is removed once the new one has been applied, which reconciles the secret again
+		return 0, nil
+	}
+	if due && !isRotatable(instance, secret) {
+		r.Recorder.Event(instance, "Warning", "WebhookSecretNotRotated", "webhook secret "+name+" was not generated by the operator, annotate it with "+allowRotationAnnotation+"=true to rotate it")
+		return 0, nil
+	}
+
+	if due {
+		value, err := generateSecretValue()
+		if err != nil {
+			log.Error(err, "unable to generate webhook secret")
+			return 0, err
+		}
+		if secret.Data == nil {
+			secret.Data = map[string][]byte{}
+		}
+		secret.Data["previousSecret"] = secret.Data["secret"]
+		secret.Data["secret"] = value
+		annotations := secret.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[rotatedAtAnnotation] = now.UTC().Format(time.RFC3339)
+		if requested {
+			annotations[rotationRequestAnnotation] = request
+		}
+		delete(annotations, rotationAppliedAtAnnotation)
+		secret.SetAnnotations(annotations)
+		err = r.Update(ctx, secret)
+		if err != nil {
+			log.Error(err, "unable to rotate webhook secret", "secret", name)
+			return 0, err
+		}
+		instance.Status.LastSecretRotationTime = &metav1.Time{Time: now}
+		if requested {
+			instance.Status.LastSecretRotationRequest = request
+		}
+		r.Recorder.Event(instance, "Normal", "WebhookSecretRotated", "rotated webhook secret "+name)
+		return 0, nil
+	}
+
+	if rotation.Interval != nil {
+		return lastRotation.Add(rotation.Interval.Duration).Sub(now), nil
+	}
+	return 0, nil
+}
+
+// expirePreviousWebhookSecret removes the "previousSecret" key once the grace period has elapsed, it is called after the webhook has been reconciled.
+// The grace period starts when the new value is found applied to the git server, the start is recorded in the annotations of the secret.
+// It returns after how long the secret must be checked again, zero when no previous value is kept.
+func (r *GitWebhookReconciler) expirePreviousWebhookSecret(ctx context.Context, instance *redhatcopv1alpha1.GitWebhook) (time.Duration, error) {
+	log := log.FromContext(ctx)
+	rotation := instance.Spec.WebhookSecret.Rotation
+	name := instance.GetWebhookSecretName()
+	if rotation == nil || name == "" || !instance.SupportsWebhookSecret() {
+		return 0, nil
+	}
+	secret, err := r.getWebhookSecret(ctx, instance)
+	if err != nil {
+		return 0, err
+	}
+	if _, found := secret.Data["previousSecret"]; !found || !isRotatable(instance, secret) {
+		return 0, nil
+	}
+	// the git server may still use the previous value, when the secret read by the last reconcile was not the rotated one yet
+	if !instance.IsWebhookSecretApplied(string(secret.Data["secret"])) {
+		return 0, nil
+	}
+	now := time.Now()
+	annotations := secret.GetAnnotations()
+	appliedAt, found := annotationTime(secret, rotationAppliedAtAnnotation)
+	if !found {
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[rotationAppliedAtAnnotation] = now.UTC().Format(time.RFC3339)
+		secret.SetAnnotations(annotations)
+		err = r.Update(ctx, secret)
+		if err != nil {
+			log.Error(err, "unable to record the application of the webhook secret", "secret", name)
+			return 0, err
+		}
+		return rotation.GracePeriod.Duration, nil
+	}
+	expiration := appliedAt.Add(rotation.GracePeriod.Duration)
+	if now.Before(expiration) {
+		return expiration.Sub(now), nil
+	}
+	delete(secret.Data, "previousSecret")
+	delete(annotations, rotationAppliedAtAnnotation)
+	secret.SetAnnotations(annotations)
+	err = r.Update(ctx, secret)
+	if err != nil {
+		log.Error(err, "unable to remove previous webhook secret", "secret", name)
+		return 0, err
+	}
+	// the secret update event triggers the reconcile that schedules the next rotation
+	return 0, nil
+}
+
+func (r *GitWebhookReconciler) getWebhookSecret(ctx context.Context, instance *redhatcopv1alpha1.GitWebhook) (*corev1.Secret, error) {
+	name := instance.GetWebhookSecretName()
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{
+		Name:      name,
+		Namespace: instance.GetNamespace(),
+	}, secret)
+	if errors.IsNotFound(err) {
+		return nil, &redhatcopv1alpha1.SecretNotFoundError{Name: name, Err: err}
+	}
+	if err != nil {
+		log.FromContext(ctx).Error(err, "unable to retrieve webhook secret", "secret", name)
+		return nil, err
+	}
+	return secret, nil
+}
+
+// isRotatable whether the operator may change the value of the webhook secret, because it generated the secret or was allowed to rotate it
+func isRotatable(instance *redhatcopv1alpha1.GitWebhook, secret *corev1.Secret) bool {
+	return metav1.IsControlledBy(secret, instance) || secret.GetAnnotations()[allowRotationAnnotation] == "true"
+}
+
+// lastSecretRotation returns when the secret was last rotated, or created when it was never rotated
+func lastSecretRotation(secret *corev1.Secret) time.Time {
+	if rotatedAt, found := annotationTime(secret, rotatedAtAnnotation); found {
+		return rotatedAt
+	}
+	return secret.CreationTimestamp.Time
+}
+
+func annotationTime(secret *corev1.Secret, annotation string) (time.Time, bool) {
+	value, found := secret.GetAnnotations()[annotation]
+	if !found {
+		return time.Time{}, false
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return parsed, true
+}
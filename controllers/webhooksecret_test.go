@@ -0,0 +1,280 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	redhatcopv1alpha1 "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newRotatedGitWebhook(annotations map[string]string) *redhatcopv1alpha1.GitWebhook {
+	return &redhatcopv1alpha1.GitWebhook{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "hook",
+			Namespace:   "default",
+			UID:         "uid",
+			Annotations: annotations,
+		},
+		Spec: redhatcopv1alpha1.GitWebhookSpec{
+			WebhookSecret: redhatcopv1alpha1.WebhookSecretReference{
+				Name: "hook-secret",
+				Rotation: &redhatcopv1alpha1.WebhookSecretRotation{
+					GracePeriod: metav1.Duration{Duration: time.Hour},
+				},
+			},
+		},
+	}
+}
+
+// newWebhookSecret returns a webhook secret generated by the GitWebhook of newRotatedGitWebhook
+func newWebhookSecret(data map[string]string, annotations map[string]string) *corev1.Secret {
+	controller := true
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "hook-secret",
+			Namespace:   "default",
+			Annotations: annotations,
+			OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: redhatcopv1alpha1.GroupVersion.String(), Kind: "GitWebhook", Name: "hook", UID: "uid", Controller: &controller},
+			},
+		},
+		Data: map[string][]byte{},
+	}
+	for key, value := range data {
+		secret.Data[key] = []byte(value)
+	}
+	return secret
+}
+
+// newTestScheme returns a scheme with the kubernetes and the operator types, the generated secrets are owned by the GitWebhooks
+func newTestScheme(t *testing.T) *runtime.Scheme {
+	testScheme := runtime.NewScheme()
+	if err := scheme.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := redhatcopv1alpha1.AddToScheme(testScheme); err != nil {
+		t.Fatal(err)
+	}
+	return testScheme
+}
+
+func newTestReconciler(t *testing.T, objects ...client.Object) *GitWebhookReconciler {
+	testScheme := newTestScheme(t)
+	return &GitWebhookReconciler{
+		Client:   fake.NewClientBuilder().WithScheme(testScheme).WithObjects(objects...).Build(),
+		Scheme:   testScheme,
+		Recorder: record.NewFakeRecorder(10),
+	}
+}
+
+func TestRotateWebhookSecret(t *testing.T) {
+	tests := []struct {
+		name          string
+		request       string
+		data          map[string]string
+		annotations   map[string]string
+		userSecret    bool
+		wantRotated   bool
+		wantPrevious  string
+		wantRequested string
+	}{
+		{
+			name:          "requested rotation",
+			request:       "1",
+			data:          map[string]string{"secret": "current"},
+			wantRotated:   true,
+			wantPrevious:  "current",
+			wantRequested: "1",
+		},
+		{
+			name:          "request already handled",
+			request:       "1",
+			data:          map[string]string{"secret": "current"},
+			annotations:   map[string]string{rotationRequestAnnotation: "1"},
+			wantRotated:   false,
+			wantRequested: "1",
+		},
+		{
+			name:         "rotation deferred while the previous value is kept",
+			request:      "2",
+			data:         map[string]string{"secret": "rotated", "previousSecret": "current"},
+			annotations:  map[string]string{rotationRequestAnnotation: "1"},
+			wantRotated:  false,
+			wantPrevious: "current",
+			// the request is handled once the previous value has expired
+			wantRequested: "1",
+		},
+		{
+			name:        "secret supplied by the user",
+			request:     "1",
+			data:        map[string]string{"secret": "current"},
+			userSecret:  true,
+			wantRotated: false,
+		},
+		{
+			name:          "secret supplied by the user and allowed to rotate",
+			request:       "1",
+			data:          map[string]string{"secret": "current"},
+			annotations:   map[string]string{allowRotationAnnotation: "true"},
+			userSecret:    true,
+			wantRotated:   true,
+			wantPrevious:  "current",
+			wantRequested: "1",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			instance := newRotatedGitWebhook(map[string]string{rotateSecretAnnotation: test.request})
+			secret := newWebhookSecret(test.data, test.annotations)
+			if test.userSecret {
+				secret.OwnerReferences = nil
+			}
+			r := newTestReconciler(t, secret)
+			_, err := r.rotateWebhookSecret(context.TODO(), instance)
+			if err != nil {
+				t.Fatalf("rotateWebhookSecret() error = %v", err)
+			}
+			secret = &corev1.Secret{}
+			if err := r.Get(context.TODO(), types.NamespacedName{Name: "hook-secret", Namespace: "default"}, secret); err != nil {
+				t.Fatal(err)
+			}
+			if rotated := string(secret.Data["secret"]) != test.data["secret"]; rotated != test.wantRotated {
+				t.Errorf("rotated = %v, want %v", rotated, test.wantRotated)
+			}
+			if previous := string(secret.Data["previousSecret"]); previous != test.wantPrevious {
+				t.Errorf("previousSecret = %q, want %q", previous, test.wantPrevious)
+			}
+			if requested := secret.Annotations[rotationRequestAnnotation]; requested != test.wantRequested {
+				t.Errorf("rotation request annotation = %q, want %q", requested, test.wantRequested)
+			}
+			if _, found := secret.Annotations[rotatedAtAnnotation]; found != test.wantRotated {
+				t.Errorf("rotated-at annotation recorded = %v, want %v", found, test.wantRotated)
+			}
+		})
+	}
+}
+
+func TestExpirePreviousWebhookSecret(t *testing.T) {
+	now := time.Now().UTC()
+	tests := []struct {
+		name            string
+		applied         string
+		annotations     map[string]string
+		wantPrevious    bool
+		wantAppliedAt   bool
+		wantRequeueSome bool
+	}{
+		{
+			name:         "rotated value not applied yet",
+			applied:      "current",
+			wantPrevious: true,
+		},
+		{
+			name:            "grace period starts when the rotated value is applied",
+			applied:         "rotated",
+			wantPrevious:    true,
+			wantAppliedAt:   true,
+			wantRequeueSome: true,
+		},
+		{
+			name:            "grace period not elapsed",
+			applied:         "rotated",
+			annotations:     map[string]string{rotationAppliedAtAnnotation: now.Add(-time.Minute).Format(time.RFC3339)},
+			wantPrevious:    true,
+			wantAppliedAt:   true,
+			wantRequeueSome: true,
+		},
+		{
+			name:        "grace period elapsed",
+			applied:     "rotated",
+			annotations: map[string]string{rotationAppliedAtAnnotation: now.Add(-2 * time.Hour).Format(time.RFC3339)},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			instance := newRotatedGitWebhook(nil)
+			instance.SetAppliedWebhookSecret(test.applied)
+			r := newTestReconciler(t, newWebhookSecret(map[string]string{"secret": "rotated", "previousSecret": "current"}, test.annotations))
+			requeueAfter, err := r.expirePreviousWebhookSecret(context.TODO(), instance)
+			if err != nil {
+				t.Fatalf("expirePreviousWebhookSecret() error = %v", err)
+			}
+			secret := &corev1.Secret{}
+			if err := r.Get(context.TODO(), types.NamespacedName{Name: "hook-secret", Namespace: "default"}, secret); err != nil {
+				t.Fatal(err)
+			}
+			if _, found := secret.Data["previousSecret"]; found != test.wantPrevious {
+				t.Errorf("previousSecret kept = %v, want %v", found, test.wantPrevious)
+			}
+			if _, found := secret.Annotations[rotationAppliedAtAnnotation]; found != test.wantAppliedAt {
+				t.Errorf("rotation-applied-at annotation = %v, want %v", found, test.wantAppliedAt)
+			}
+			if (requeueAfter > 0) != test.wantRequeueSome {
+				t.Errorf("requeueAfter = %v, want a requeue %v", requeueAfter, test.wantRequeueSome)
+			}
+		})
+	}
+}
+
+func TestEnsureWebhookSecret(t *testing.T) {
+	tests := []struct {
+		name          string
+		secretName    string
+		generate      bool
+		existing      *corev1.Secret
+		wantGenerated bool
+		wantName      string
+	}{
+		{name: "named secret generated when missing", secretName: "hook-secret", wantGenerated: true, wantName: "hook-secret"},
+		{name: "secret named after the GitWebhook", generate: true, wantGenerated: true, wantName: "hook-webhook-secret"},
+		{name: "existing secret kept", secretName: "hook-secret", existing: newWebhookSecret(map[string]string{"secret": "current"}, nil), wantName: "hook-secret"},
+		{name: "no secret", wantGenerated: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			instance := newRotatedGitWebhook(nil)
+			instance.Spec.WebhookSecret = redhatcopv1alpha1.WebhookSecretReference{Name: test.secretName, Generate: test.generate}
+			instance.Spec.GitHub = &redhatcopv1alpha1.GitHubServerConfig{}
+			objects := []client.Object{}
+			if test.existing != nil {
+				objects = append(objects, test.existing)
+			}
+			r := newTestReconciler(t, objects...)
+			generated, err := r.ensureWebhookSecret(context.TODO(), instance)
+			if err != nil {
+				t.Fatalf("ensureWebhookSecret() error = %v", err)
+			}
+			if generated != test.wantGenerated {
+				t.Errorf("generated = %v, want %v", generated, test.wantGenerated)
+			}
+			if test.wantName == "" {
+				return
+			}
+			secret := &corev1.Secret{}
+			if err := r.Get(context.TODO(), types.NamespacedName{Name: test.wantName, Namespace: "default"}, secret); err != nil {
+				t.Fatal(err)
+			}
+			if test.existing != nil {
+				if string(secret.Data["secret"]) != "current" {
+					t.Errorf("existing secret changed to %q", secret.Data["secret"])
+				}
+				return
+			}
+			if len(secret.Data["secret"]) != 64 {
+				t.Errorf("generated secret = %q, want 32 random bytes in hex", secret.Data["secret"])
+			}
+			if !metav1.IsControlledBy(secret, instance) {
+				t.Errorf("generated secret not owned by the GitWebhook: %+v", secret.OwnerReferences)
+			}
+		})
+	}
+}
@@ -19,6 +19,7 @@ package main
 import (
 	"flag"
 	"os"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -33,6 +34,15 @@ import (
 
 	redhatcopv1alpha1 "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1"
 	"github.com/redhat-cop/gitwebhook-operator/controllers"
+
+	// Register the git server providers, in-house providers can be added here.
+	_ "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1/azuredevops"
+	_ "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1/bitbucket"
+	_ "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1/bitbucketdatacenter"
+	_ "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1/gerrit"
+	_ "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1/gitea"
+	_ "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1/github"
+	_ "github.com/redhat-cop/gitwebhook-operator/api/v1alpha1/gitlab"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -52,11 +62,15 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var resyncInterval time.Duration
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.DurationVar(&resyncInterval, "resync-interval", time.Hour,
+		"How often the webhooks are compared with the git servers to repair the changes made outside of the operator, "+
+			"unless the GitWebhooks set their own resyncInterval. 0 disables the periodic resync.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -90,9 +104,10 @@ func main() {
 	}
 
 	if err = (&controllers.GitWebhookReconciler{
-		Client:   mgr.GetClient(),
-		Scheme:   mgr.GetScheme(),
-		Recorder: mgr.GetEventRecorderFor("gitwebhook"),
+		Client:         mgr.GetClient(),
+		Scheme:         mgr.GetScheme(),
+		Recorder:       mgr.GetEventRecorderFor("gitwebhook"),
+		ResyncInterval: resyncInterval,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "GitWebhook")
 		os.Exit(1)
@@ -101,6 +116,22 @@ func main() {
 		setupLog.Error(err, "unable to create webhook", "webhook", "GitWebhook")
 		os.Exit(1)
 	}
+	if err = (&controllers.GitLabSystemHookReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("gitlabsystemhook"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "GitLabSystemHook")
+		os.Exit(1)
+	}
+	if err = (&controllers.GitHubGlobalHookReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("githubglobalhook"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "GitHubGlobalHook")
+		os.Exit(1)
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {